@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+const authorsPath = "authors.yaml"
+
+// Author is a profile from authors.yaml, referenced from posts by its key.
+type Author struct {
+	Key    string       `yaml:"-"`
+	Name   string       `yaml:"name"`
+	Bio    string       `yaml:"bio"`
+	Avatar string       `yaml:"avatar"`
+	URL    string       `yaml:"url"`
+	Email  string       `yaml:"email"`
+	Links  []AuthorLink `yaml:"links"`
+}
+
+type AuthorLink struct {
+	Name string `yaml:"name"`
+	URL  string `yaml:"url"`
+}
+
+// PageURL returns the site-relative URL of the author's page.
+func (a Author) PageURL() string {
+	return "/authors/" + a.Key + ".html"
+}
+
+var authors map[string]Author
+
+func loadAuthors(path string) (map[string]Author, error) {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read authors %s: %w", path, err)
+	}
+
+	var profiles map[string]Author
+	if err := yaml.Unmarshal(content, &profiles); err != nil {
+		return nil, fmt.Errorf("invalid authors %s: %w", path, err)
+	}
+	for key, a := range profiles {
+		if a.Name == "" {
+			return nil, fmt.Errorf("author %q in %s has no name", key, path)
+		}
+		a.Key = key
+		profiles[key] = a
+	}
+	return profiles, nil
+}
+
+// resolveAuthors maps a post's author keys to profiles, warning about keys
+// missing from authors.yaml.
+func resolveAuthors(filename string, keys []string) []Author {
+	var resolved []Author
+	for _, key := range keys {
+		a, ok := authors[key]
+		if !ok {
+			warnf("unknown author %q in %s", key, filename)
+			continue
+		}
+		resolved = append(resolved, a)
+	}
+	return resolved
+}
+
+type AuthorData struct {
+	Author
+	Posts []Post
+	GAID  string
+}
+
+func generateAuthorPages(posts []Post) error {
+	if len(authors) == 0 {
+		return nil
+	}
+	if err := mkdirOutput("public/authors"); err != nil {
+		return fmt.Errorf("create authors dir: %w", err)
+	}
+
+	for key, a := range authors {
+		var authored []Post
+		for _, post := range posts {
+			for _, pa := range post.Authors {
+				if pa.Key == key {
+					authored = append(authored, post)
+					break
+				}
+			}
+		}
+
+		path := filepath.Join("public", "authors", key+".html")
+		f, err := createOutput(path)
+		if err != nil {
+			return fmt.Errorf("create author page %s: %w", path, err)
+		}
+
+		if err := authorTmpl.Execute(f, AuthorData{Author: a, Posts: authored, GAID: gaID}); err != nil {
+			f.Close()
+			return fmt.Errorf("render author %s: %w", key, err)
+		}
+		f.Close()
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const buildInfoPath = "public/build.json"
+
+// buildInfoFields are the fields build.json can include.
+var buildInfoFields = []string{"time", "commit", "hash"}
+
+// BuildInfo is the content of public/build.json. Fields not in
+// cfg.BuildInfo.Fields are left out.
+type BuildInfo struct {
+	Time   string `json:"time,omitempty"`
+	Commit string `json:"commit,omitempty"`
+	// Hash covers the path and content of every other file this build
+	// wrote, so it changes with any change to the deployed site.
+	Hash string `json:"hash,omitempty"`
+}
+
+// generateBuildInfo writes public/build.json, for clients to detect new
+// deploys cheaply. It must run after every other output is written.
+func generateBuildInfo() error {
+	if !cfg.BuildInfo.Enabled {
+		return nil
+	}
+
+	var info BuildInfo
+	fields := cfg.BuildInfo.Fields
+	if slices.Contains(fields, "time") {
+		info.Time = buildTime.UTC().Format(time.RFC3339)
+	}
+	if slices.Contains(fields, "commit") {
+		// Builds from a tarball or a Docker context have no commit, which
+		// is no reason to fail --strict.
+		if out, err := exec.Command("git", "rev-parse", "HEAD").Output(); err == nil {
+			info.Commit = strings.TrimSpace(string(out))
+		} else {
+			verbosef("build.json: no git commit: %v", err)
+		}
+	}
+	if slices.Contains(fields, "hash") {
+		hash, err := outputsHash()
+		if err != nil {
+			return err
+		}
+		info.Hash = hash
+	}
+
+	content, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("encode build info: %w", err)
+	}
+	if err := writeOutput(buildInfoPath, append(content, '\n')); err != nil {
+		return fmt.Errorf("write build info: %w", err)
+	}
+	return nil
+}
+
+// sourceDate returns the time of the build: SOURCE_DATE_EPOCH, in seconds
+// since the Unix epoch, when it is set, for reproducible builds, and the
+// current time otherwise. build sets buildTime from it, so build.json,
+// the sitemap, feeds and every date-dependent page agree.
+func sourceDate() (time.Time, error) {
+	epoch := os.Getenv("SOURCE_DATE_EPOCH")
+	if epoch == "" {
+		return time.Now(), nil
+	}
+	secs, err := strconv.ParseInt(epoch, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q: %w", epoch, err)
+	}
+	return time.Unix(secs, 0), nil
+}
+
+// outputsHash hashes the paths and contents of the files written so far,
+// in path order.
+func outputsHash() (string, error) {
+	outputsMu.Lock()
+	files := make([]string, 0, len(outputs))
+	for out := range outputs {
+		if out != buildInfoPath {
+			files = append(files, out)
+		}
+	}
+	outputsMu.Unlock()
+	sort.Strings(files)
+
+	h := sha256.New()
+	for _, file := range files {
+		sum, err := hashFile(file)
+		if err != nil {
+			return "", fmt.Errorf("hash outputs: %w", err)
+		}
+		fmt.Fprintf(h, "%s %s\n", sum, strings.TrimPrefix(file, "public/"))
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSourceDate(t *testing.T) {
+	tests := []struct {
+		epoch   string
+		want    time.Time
+		wantErr bool
+	}{
+		{"0", time.Unix(0, 0), false},
+		{"1700000000", time.Unix(1700000000, 0), false},
+		{"soon", time.Time{}, true},
+		{"1.5", time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Setenv("SOURCE_DATE_EPOCH", tt.epoch)
+		got, err := sourceDate()
+		if (err != nil) != tt.wantErr || !got.Equal(tt.want) {
+			t.Errorf("sourceDate() with %q = %v, %v, want %v, error: %v", tt.epoch, got, err, tt.want, tt.wantErr)
+		}
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+	before := time.Now()
+	if got, err := sourceDate(); err != nil || got.Before(before) || got.After(time.Now()) {
+		t.Errorf("sourceDate() unset = %v, %v, want the current time", got, err)
+	}
+}
+
+// TestBuildTimeFromSourceDate checks that SOURCE_DATE_EPOCH decides which
+// posts are published, like build.json's time.
+func TestBuildTimeFromSourceDate(t *testing.T) {
+	saved := buildTime
+	t.Cleanup(func() { buildTime = saved })
+	t.Setenv("SOURCE_DATE_EPOCH", "1704067200") // 2024-01-01
+	buildSite(t, "", map[string]string{"before": "date: 2023-12-31", "after": "date: 2024-01-02"})
+	if want := time.Unix(1704067200, 0); !buildTime.Equal(want) {
+		t.Errorf("buildTime = %v, want %v", buildTime, want)
+	}
+	content, err := os.ReadFile("public/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := listedSlugs(string(content), "before", "after"); !slices.Equal(got, []string{"before"}) {
+		t.Errorf("feed.xml lists %v, want [before]", got)
+	}
+}
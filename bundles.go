@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// bundleURLs maps every bundle name to the site-relative URL it was
+	// written to, for the bundle template function.
+	bundleURLs = map[string]string{}
+
+	// bundledFiles holds the static sources that went into a bundle, which
+	// copyStaticFiles leaves out.
+	bundledFiles = map[string]bool{}
+
+	// bundledAs maps every bundled file, relative to static/, to the URL of
+	// its bundle, for the asset template function.
+	bundledAs = map[string]string{}
+)
+
+// bundleURL returns the URL of the named bundle.
+func bundleURL(name string) (string, error) {
+	url, ok := bundleURLs[name]
+	if !ok {
+		return "", fmt.Errorf("unknown bundle %q", name)
+	}
+	return url, nil
+}
+
+// assetURL returns the URL serving the static file, relative to static/:
+// that of the bundle it went into, or else its own. Templates link the
+// site's stylesheet and scripts through it, so bundling them needs no
+// template changes.
+func assetURL(file string) string {
+	if url, ok := bundledAs[file]; ok {
+		return url
+	}
+	return "/" + file
+}
+
+// generateBundles writes every configured bundle to dstDir, concatenating
+// its files from srcDir in order.
+func generateBundles(srcDir, dstDir string) error {
+	for _, b := range cfg.Bundles {
+		var buf bytes.Buffer
+		for _, file := range b.Files {
+			src := filepath.Join(srcDir, filepath.FromSlash(file))
+			content, err := os.ReadFile(src)
+			if err != nil {
+				return fmt.Errorf("bundle %q: %w", b.Name, err)
+			}
+			buf.Write(content)
+			if !bytes.HasSuffix(content, []byte("\n")) {
+				buf.WriteByte('\n')
+			}
+			if path.Ext(b.Name) == ".js" && !bytes.HasSuffix(bytes.TrimSpace(content), []byte(";")) {
+				// Keep a file without a trailing semicolon from running
+				// into the next one.
+				buf.WriteString(";\n")
+			}
+			bundledFiles[src] = true
+		}
+
+		content := buf.Bytes()
+		if b.Minify {
+			var err error
+			if content, err = minifyCSS(content); err != nil {
+				return fmt.Errorf("bundle %q: %w", b.Name, err)
+			}
+		}
+
+		name := b.Name
+		if b.Fingerprint {
+			sum := sha256.Sum256(content)
+			ext := path.Ext(name)
+			name = strings.TrimSuffix(name, ext) + "." + hex.EncodeToString(sum[:4]) + ext
+		}
+
+		dst := filepath.Join(dstDir, filepath.FromSlash(name))
+		if err := mkdirOutput(filepath.Dir(dst)); err != nil {
+			return fmt.Errorf("create bundle dir for %s: %w", dst, err)
+		}
+		if err := writeOutput(dst, content); err != nil {
+			return fmt.Errorf("write bundle %s: %w", dst, err)
+		}
+		bundleURLs[b.Name] = "/" + name
+		for _, file := range b.Files {
+			bundledAs[file] = "/" + name
+		}
+	}
+	return nil
+}
+
+// minifyCSS drops comments and collapses whitespace, removing it around
+// braces, semicolons and commas. Quoted strings are kept as written. A
+// comment that is never closed is an error, as it would hide the rest.
+func minifyCSS(css []byte) ([]byte, error) {
+	var out []byte
+	space := false
+	for i := 0; i < len(css); i++ {
+		c := css[i]
+		switch {
+		case c == '/' && i+1 < len(css) && css[i+1] == '*':
+			end := bytes.Index(css[i+2:], []byte("*/"))
+			if end < 0 {
+				return nil, fmt.Errorf("comment at byte %d is never closed", i)
+			}
+			i += end + 3
+			space = true
+		case c == '"' || c == '\'':
+			out = appendSpace(out, space)
+			space = false
+			start := i
+			for i++; i < len(css) && css[i] != c; i++ {
+				if css[i] == '\\' {
+					i++
+				}
+			}
+			out = append(out, css[start:min(i+1, len(css))]...)
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f':
+			space = true
+		case strings.IndexByte("{};,", c) >= 0:
+			if c == '}' && len(out) > 0 && out[len(out)-1] == ';' {
+				out = out[:len(out)-1]
+			}
+			out = append(out, c)
+			space = false
+		default:
+			out = appendSpace(out, space)
+			space = false
+			out = append(out, c)
+		}
+	}
+	return append(out, '\n'), nil
+}
+
+// appendSpace appends the single space standing for a run of whitespace,
+// unless it would follow punctuation that needs none.
+func appendSpace(out []byte, space bool) []byte {
+	if !space || len(out) == 0 || strings.IndexByte("{};,", out[len(out)-1]) >= 0 {
+		return out
+	}
+	return append(out, ' ')
+}
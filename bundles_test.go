@@ -0,0 +1,37 @@
+package main
+
+import "testing"
+
+func TestMinifyCSS(t *testing.T) {
+	tests := []struct {
+		css     string
+		want    string
+		wantErr bool
+	}{
+		{"a {\n  color: red;\n}\n", "a{color: red}\n", false},
+		{"/* note */ a , b { margin : 0 }", "a,b{margin : 0}\n", false},
+		{"a { content: \"/* kept */\" }", "a{content: \"/* kept */\"}\n", false},
+		{"a { color: red } /* never closed\nb { color: blue }", "", true},
+	}
+	for _, tt := range tests {
+		got, err := minifyCSS([]byte(tt.css))
+		if (err != nil) != tt.wantErr || string(got) != tt.want {
+			t.Errorf("minifyCSS(%q) = %q, %v, want %q, error: %v", tt.css, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestAssetURL(t *testing.T) {
+	saved := bundledAs
+	t.Cleanup(func() { bundledAs = saved })
+	bundledAs = map[string]string{"main.css": "/css/site.1a2b3c4d.css"}
+	tests := map[string]string{
+		"main.css": "/css/site.1a2b3c4d.css",
+		"copy.js":  "/copy.js",
+	}
+	for file, want := range tests {
+		if got := assetURL(file); got != want {
+			t.Errorf("assetURL(%q) = %q, want %q", file, got, want)
+		}
+	}
+}
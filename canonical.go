@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// normalizeCanonical turns a canonical front matter value into an absolute
+// URL. Relative values are resolved against siteURL; absolute ones must be
+// http(s) URLs with a host.
+func normalizeCanonical(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+	if u.IsAbs() || u.Host != "" {
+		if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+			return "", fmt.Errorf("%q is not an http(s) URL", raw)
+		}
+		return u.String(), nil
+	}
+	base, err := url.Parse(cfg.SiteURL + "/")
+	if err != nil {
+		return "", err
+	}
+	return base.ResolveReference(u).String(), nil
+}
+
+// checkCanonicals warns about canonical URLs that point into the site but
+// at no page it builds. Pages resolve as with pageFile, so /post, /post.html
+// and /dir/ style canonicals are all recognized.
+func checkCanonicals(posts []Post) {
+	pages := map[string]bool{"index.html": true}
+	for _, p := range posts {
+		pages[p.Path] = true
+	}
+	exists := func(rel string) bool {
+		if pages[rel] {
+			return true
+		}
+		info, err := os.Stat("static/" + rel)
+		return err == nil && !info.IsDir()
+	}
+	for _, p := range posts {
+		if p.Canonical == "" {
+			continue
+		}
+		path, ok := internalPath(p.Canonical)
+		if !ok {
+			continue
+		}
+		if _, ok := pageFile(path, exists); !ok {
+			warnf("%s: canonical %s points to a page that does not exist", p.Filename, p.Canonical)
+		}
+	}
+}
+
+// internalPath returns the site-relative path of an absolute URL on the
+// host of siteURL or alternateSiteURL.
+func internalPath(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	for _, site := range []string{cfg.SiteURL, cfg.AlternateSiteURL} {
+		s, err := url.Parse(site)
+		if err != nil || site == "" || !strings.EqualFold(s.Host, u.Host) {
+			continue
+		}
+		prefix := strings.TrimSuffix(s.Path, "/") + "/"
+		if u.Path == strings.TrimSuffix(prefix, "/") {
+			return "", true
+		}
+		if strings.HasPrefix(u.Path, prefix) {
+			return strings.TrimPrefix(u.Path, prefix), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestInternalPath(t *testing.T) {
+	tests := []struct {
+		raw    string
+		want   string
+		wantOK bool
+	}{
+		{"https://example.com/post.html", "post.html", true},
+		{"https://EXAMPLE.com/post.html", "post.html", true},
+		{"http://example.com/dir/", "dir/", true},
+		{"https://example.com", "", true},
+		{"https://example.com/", "", true},
+		{"https://www.example.org/blog/post.html", "post.html", true},
+		{"https://www.example.org/blog", "", true},
+		{"https://www.example.org/blogroll.html", "", false},
+		{"https://www.example.org/post.html", "", false},
+		{"https://other.com/post.html", "", false},
+		{"/post.html", "", false},
+		{"%zz", "", false},
+	}
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	cfg.SiteURL = "https://example.com"
+	cfg.AlternateSiteURL = "https://www.example.org/blog"
+	for _, tt := range tests {
+		got, ok := internalPath(tt.raw)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("internalPath(%q) = %q, %v, want %q, %v", tt.raw, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"slices"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var kindCodeCopy = ast.NewNodeKind("CodeCopy")
+
+// codeCopyNode wraps a fenced code block that gets a copy button. copy is
+// the text to copy when it differs from the block's, as for prompt
+// languages.
+type codeCopyNode struct {
+	ast.BaseBlock
+	copy []byte
+}
+
+func (n *codeCopyNode) Kind() ast.NodeKind { return kindCodeCopy }
+
+func (n *codeCopyNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// codeCopyTransformer wraps the fenced code blocks of the languages in
+// cfg.CodeCopy in a codeCopyNode.
+type codeCopyTransformer struct{}
+
+func (codeCopyTransformer) Transform(doc *ast.Document, reader text.Reader, _ parser.Context) {
+	cc := cfg.CodeCopy
+	if len(cc.Languages) == 0 && len(cc.PromptLanguages) == 0 {
+		return
+	}
+
+	source := reader.Source()
+	var blocks []*ast.FencedCodeBlock
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if b, ok := n.(*ast.FencedCodeBlock); ok && entering {
+			blocks = append(blocks, b)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, b := range blocks {
+		lang := string(b.Language(source))
+		prompt := slices.Contains(cc.PromptLanguages, lang)
+		if !prompt && !slices.Contains(cc.Languages, lang) && !(lang != "" && slices.Contains(cc.Languages, "*")) {
+			continue
+		}
+		wrapper := &codeCopyNode{}
+		if prompt {
+			wrapper.copy = stripPrompts(b.Lines().Value(source))
+		}
+		b.Parent().ReplaceChild(b.Parent(), b, wrapper)
+		wrapper.AppendChild(wrapper, b)
+	}
+}
+
+// stripPrompts returns the commands of a shell session: the lines starting
+// with a "$ " or "# " prompt, without it. Other lines are taken to be
+// output and dropped, unless no line has a prompt.
+func stripPrompts(code []byte) []byte {
+	var commands [][]byte
+	for _, line := range bytes.Split(bytes.TrimRight(code, "\n"), []byte("\n")) {
+		trimmed := bytes.TrimLeft(line, " \t")
+		for _, prompt := range [][]byte{[]byte("$ "), []byte("# ")} {
+			if bytes.HasPrefix(trimmed, prompt) {
+				commands = append(commands, trimmed[len(prompt):])
+				break
+			}
+		}
+	}
+	if len(commands) == 0 {
+		return nil
+	}
+	return append(bytes.Join(commands, []byte("\n")), '\n')
+}
+
+// hasCopyButtons reports whether the transformer gave any block in doc a
+// copy button.
+func hasCopyButtons(doc ast.Node) bool {
+	found := false
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if _, ok := n.(*codeCopyNode); ok {
+			found = true
+			return ast.WalkStop, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return found
+}
+
+type codeCopyRenderer struct{}
+
+func (codeCopyRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindCodeCopy, func(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		n := node.(*codeCopyNode)
+		if !entering {
+			_, _ = w.WriteString("</div>\n")
+			return ast.WalkContinue, nil
+		}
+		_, _ = w.WriteString("<div class=\"code-block\"")
+		if n.copy != nil {
+			_, _ = w.WriteString(" data-copy=\"")
+			_, _ = w.Write(util.EscapeHTML(n.copy))
+			_ = w.WriteByte('"')
+		}
+		_, _ = w.WriteString(">\n<button type=\"button\" class=\"code-copy\">Copy</button>\n")
+		return ast.WalkContinue, nil
+	})
+}
@@ -0,0 +1,797 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"gopkg.in/yaml.v3"
+)
+
+const configPath = "config.yaml"
+
+// siteConfig holds the optional build settings read from config.yaml. The
+// zero value of every field keeps the generator's default behavior, so the
+// file can be omitted entirely.
+type siteConfig struct {
+	// SiteURL is the canonical origin used for every absolute URL the site
+	// emits. Defaults to defaultSiteURL.
+	SiteURL string `yaml:"siteURL"`
+	// AlternateSiteURL is an optional secondary origin serving the same
+	// content. The sitemap lists it as an alternate of each canonical URL.
+	AlternateSiteURL string `yaml:"alternateSiteURL"`
+
+	// PathRewrites maps a relative path prefix used in post markdown to the
+	// prefix it is served under, e.g. "images/" -> "/images/".
+	PathRewrites map[string]string `yaml:"pathRewrites"`
+
+	ReadingTime readingTimeConfig `yaml:"readingTime"`
+
+	// SearchURLTemplate enables the WebSite SearchAction JSON-LD on the
+	// index. It must contain {search_term_string} and may be relative to
+	// siteURL, e.g. "/search?q={search_term_string}".
+	SearchURLTemplate string `yaml:"searchURLTemplate"`
+
+	// ImageFormats lists the modern formats ("webp", "avif") generated for
+	// local JPEG and PNG images and offered through <picture>. Formats whose
+	// encoder is not installed are skipped with a warning.
+	ImageFormats []string `yaml:"imageFormats"`
+
+	ImageQuality imageQualityConfig `yaml:"imageQuality"`
+
+	QRCodes qrCodesConfig `yaml:"qrCodes"`
+
+	BuildInfo buildInfoConfig `yaml:"buildInfo"`
+
+	// DefaultCover is the share image, relative to static/, of posts with
+	// neither a cover nor a generated social image.
+	DefaultCover string `yaml:"defaultCover"`
+	// RequireCover warns about publishable posts without a cover, unless
+	// DefaultCover is set or their content type has CoverOptional.
+	RequireCover bool `yaml:"requireCover"`
+
+	Descriptions descriptionsConfig `yaml:"descriptions"`
+
+	Sitemap sitemapConfig `yaml:"sitemap"`
+
+	// FeedIDStrategy selects how feed entry IDs are built: "url" (default),
+	// "slug" or "hash". See Post.FeedID.
+	FeedIDStrategy string `yaml:"feedIDStrategy"`
+
+	// StaleAfterYears marks posts older than this many years as possibly
+	// outdated. Zero disables the notice.
+	StaleAfterYears int `yaml:"staleAfterYears"`
+
+	Permissions permissionsConfig `yaml:"permissions"`
+
+	// SourceEncoding is the encoding posts are written in, like
+	// "windows-1254", when it is not UTF-8. Names are those of the WHATWG
+	// Encoding Standard.
+	SourceEncoding string `yaml:"sourceEncoding"`
+	// InvalidUTF8 is what happens to posts that are not valid UTF-8:
+	// "error" (default) fails the build naming the offset of the first
+	// bad byte, "replace" warns and replaces each run of bad bytes with
+	// U+FFFD.
+	InvalidUTF8 string `yaml:"invalidUTF8"`
+	// FilenameDates takes the date of posts named like
+	// 2024-03-15-title.md from the filename when front matter has none,
+	// and leaves the prefix out of their slug.
+	FilenameDates bool `yaml:"filenameDates"`
+
+	// CheckLinks reports links in posts to pages of the site that the build
+	// does not produce. See checkLinks.
+	CheckLinks bool `yaml:"checkLinks"`
+
+	// ProtectedPaths are site-relative paths or path.Match patterns, like
+	// "CNAME" or "404.html", that are never overwritten once they exist.
+	// See protectedOutput.
+	ProtectedPaths []string `yaml:"protectedPaths"`
+	// StaticFirst copies static/ before generating pages instead of after,
+	// so a protected static file wins over a generated one.
+	StaticFirst bool `yaml:"staticFirst"`
+
+	Feeds feedsConfig `yaml:"feeds"`
+
+	// Timezone is the IANA zone for front matter dates without a UTC
+	// offset. Defaults to UTC.
+	Timezone string `yaml:"timezone"`
+	location *time.Location
+	// TimeLayout formats the time of day on posts with showTime, as a Go
+	// time layout. Defaults to "15:04".
+	TimeLayout string `yaml:"timeLayout"`
+
+	// UnicodeSlugs selects how non-ASCII letters and digits appear in
+	// heading anchors and slugs: "keep" (default), "strip" or "percent" to
+	// percent-encode them. Emoji are always dropped.
+	UnicodeSlugs string `yaml:"unicodeSlugs"`
+
+	// DeployCommand is the shell command run by the deploy subcommand
+	// after a successful build, e.g. "git -C $OUTPUT_DIR push".
+	DeployCommand string `yaml:"deployCommand"`
+
+	// SummaryFrom names a heading, like "TL;DR", whose section becomes the
+	// summary of posts that have it. Posts can override it in front matter.
+	SummaryFrom string `yaml:"summaryFrom"`
+
+	// Language is the language code of posts without a lang, and defaults
+	// to "en".
+	Language string `yaml:"language"`
+
+	ExternalLinks externalLinksConfig `yaml:"externalLinks"`
+
+	SocialImages socialImagesConfig `yaml:"socialImages"`
+
+	// ContentTypes lists the kinds of content to build, each from its own
+	// directory. Defaults to defaultContentTypes.
+	ContentTypes []contentType `yaml:"contentTypes"`
+
+	// Site holds values posts can use as {{ site.name }}.
+	Site map[string]any `yaml:"site"`
+
+	// PrintStylesheet is the URL of the print stylesheet linked from
+	// printable posts. Defaults to "/print.css".
+	PrintStylesheet string `yaml:"printStylesheet"`
+
+	// Redirects maps old page paths to current ones, e.g.
+	// "old-slug.html" -> "new-slug.html". Renamed posts are usually
+	// detected without one; see resolveRedirects.
+	Redirects map[string]string `yaml:"redirects"`
+	// Removed lists page paths deleted on purpose, which are then no
+	// longer reported as vanished.
+	Removed []string `yaml:"removed"`
+
+	// IndexSummaries shows each post's summary under its title on the
+	// index.
+	IndexSummaries bool `yaml:"indexSummaries"`
+
+	CodeCopy codeCopyConfig `yaml:"codeCopy"`
+
+	// RawHTML selects what happens to HTML written in posts: "omit"
+	// (default) replaces it with a comment, "unsafe" renders it as is,
+	// "escape" shows it as text and "sanitize" keeps only markup that
+	// cannot run scripts.
+	RawHTML string `yaml:"rawHTML"`
+
+	// LeadParagraph styles the first paragraph of posts that start with
+	// one as a lead, with class="lead". Posts can override it with lead.
+	LeadParagraph bool `yaml:"leadParagraph"`
+
+	// Sidenotes renders footnotes as sidenotes next to their first
+	// reference instead of a list at the bottom. Posts can override it
+	// with sidenotes.
+	Sidenotes bool `yaml:"sidenotes"`
+
+	// EditURLTemplate links every post to where its source can be edited,
+	// e.g. "https://github.com/user/repo/edit/main/posts/{filename}".
+	// {path} is replaced with the path including the content directory.
+	EditURLTemplate string `yaml:"editURLTemplate"`
+
+	// Author is the site's author, listed in the feeds and used for
+	// entries of posts without authors. Only name, email and url are
+	// used. Defaults to defaultAuthor.
+	Author Author `yaml:"author"`
+
+	// DuplicateTitles checks for posts sharing a title, which makes
+	// listings and search results ambiguous: "warn" reports them, "error"
+	// fails the build. Unset skips the check. Translations of each other
+	// may share a title.
+	DuplicateTitles string `yaml:"duplicateTitles"`
+
+	// RelatedPosts is how many related posts, sharing the most terms, are
+	// listed under each post. Zero lists none, except for posts naming
+	// theirs in front matter.
+	RelatedPosts int `yaml:"relatedPosts"`
+
+	// Bundles are static files concatenated into one output each.
+	Bundles []bundleConfig `yaml:"bundles"`
+
+	// Taxonomies lists the front matter keys that group posts into terms,
+	// each with a page per term. Defaults to defaultTaxonomies.
+	Taxonomies []taxonomyConfig `yaml:"taxonomies"`
+
+	Series seriesConfig `yaml:"series"`
+}
+
+// codeCopyConfig chooses the fenced code blocks that get a copy button, by
+// language. None do by default.
+type codeCopyConfig struct {
+	// Languages get a plain copy button; "*" stands for every block with a
+	// language.
+	Languages []string `yaml:"languages"`
+	// PromptLanguages, like console, are shell sessions. Their button
+	// copies only the commands, without the "$ " or "# " prompts.
+	PromptLanguages []string `yaml:"promptLanguages"`
+}
+
+// bundleConfig is one bundle of static CSS or JS files.
+type bundleConfig struct {
+	// Name is the bundle's path in public, e.g. "site.css". Templates get
+	// its URL with {{bundle "site.css"}}.
+	Name string `yaml:"name"`
+	// Files are paths under static/, concatenated in order.
+	Files []string `yaml:"files"`
+	// Minify strips comments and whitespace from CSS bundles.
+	Minify bool `yaml:"minify"`
+	// Fingerprint adds a hash of the content to the file name, so the
+	// bundle can be cached indefinitely.
+	Fingerprint bool `yaml:"fingerprint"`
+}
+
+// taxonomyConfig is one way of grouping posts, like tags.
+type taxonomyConfig struct {
+	// Name is the front matter key holding a post's terms, as a string or
+	// a list.
+	Name string `yaml:"name"`
+	// URLPrefix is prepended to the term page paths. Defaults to the name
+	// followed by "/".
+	URLPrefix string `yaml:"urlPrefix"`
+	// Template renders the term pages. Defaults to taxonomy.gohtml.
+	Template string `yaml:"template"`
+	// Feed also writes an Atom feed per term next to its page.
+	Feed bool `yaml:"feed"`
+	// Values, when set, are the terms the taxonomy expects, like
+	// beginner, intermediate and advanced; others are reported.
+	Values []string `yaml:"values"`
+	// Badge shows a post's terms as badges linking to their pages, in
+	// the order the taxonomies are listed.
+	Badge bool `yaml:"badge"`
+}
+
+// seriesConfig groups the parts of multi-part series in the index. See
+// indexEntries.
+type seriesConfig struct {
+	// Taxonomy names the taxonomy whose terms are series, like "series".
+	// Empty disables series.
+	Taxonomy string `yaml:"taxonomy"`
+	// ListParts also lists the parts of a series individually in the
+	// index, besides the series.
+	ListParts bool `yaml:"listParts"`
+}
+
+var defaultTaxonomies = []taxonomyConfig{
+	{Name: "tags"},
+	{Name: "categories"},
+}
+
+// contentType is one kind of content, like posts or notes.
+type contentType struct {
+	Name string `yaml:"name"`
+	// Dir holds the type's markdown files.
+	Dir string `yaml:"dir"`
+	// Template renders the type's pages unless a page sets a layout.
+	// Defaults to the template named after the type, like notes.gohtml,
+	// if there is one, and else post.gohtml.
+	Template string `yaml:"template"`
+	// URLPrefix is prepended to the page paths, e.g. "notes/".
+	URLPrefix string `yaml:"urlPrefix"`
+	// Feed, Index and Sitemap include the type in the feeds, the index
+	// and the other listing pages, and the sitemap.
+	Feed    bool `yaml:"feed"`
+	Index   bool `yaml:"index"`
+	Sitemap bool `yaml:"sitemap"`
+	// CoverOptional exempts the type from requireCover, as for notes.
+	CoverOptional bool `yaml:"coverOptional"`
+	// DescriptionOptional exempts the type from descriptions.check.
+	DescriptionOptional bool `yaml:"descriptionOptional"`
+}
+
+var defaultContentTypes = []contentType{
+	{Name: "posts", Dir: "posts", Feed: true, Index: true, Sitemap: true},
+}
+
+// socialImagesConfig controls the social cards generated for posts without
+// a cover. Setting Font enables them.
+type socialImagesConfig struct {
+	// Font is the path of a TrueType or OpenType font file.
+	Font string `yaml:"font"`
+	// Background is an optional PNG or JPEG, scaled and cropped to
+	// 1200×630. Without one the card is BackgroundColor, by default the
+	// site's background.
+	Background      string `yaml:"background"`
+	BackgroundColor string `yaml:"backgroundColor"`
+	// TextColor defaults to the site's heading color.
+	TextColor string `yaml:"textColor"`
+	// ShowDate and ShowAuthor add the date and authors below the title.
+	ShowDate   bool `yaml:"showDate"`
+	ShowAuthor bool `yaml:"showAuthor"`
+	// Format is "png" (default) or "jpeg", encoded at
+	// imageQuality.jpeg.
+	Format string `yaml:"format"`
+}
+
+// qrCodesConfig controls the QR codes generated for every post.
+type qrCodesConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Recovery is the error correction: "low", "medium" (default), "high"
+	// or "highest". URLs too long for it get the highest level they fit.
+	Recovery string `yaml:"recovery"`
+	// ModuleSize is the size of each module in pixels, 8 by default. The
+	// image grows with the number of modules a URL needs.
+	ModuleSize int `yaml:"moduleSize"`
+}
+
+// descriptionsConfig bounds the length of post descriptions, which search
+// engines cut off at around 160 characters. See checkDescriptions.
+type descriptionsConfig struct {
+	Check bool `yaml:"check"`
+	// MinLength and MaxLength are in characters, 50 and 160 by default.
+	MinLength int `yaml:"minLength"`
+	MaxLength int `yaml:"maxLength"`
+}
+
+// buildInfoConfig controls public/build.json. See generateBuildInfo.
+type buildInfoConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Fields lists the fields to include, of "time", "commit" and "hash".
+	// All of them by default.
+	Fields []string `yaml:"fields"`
+}
+
+// imageQualityConfig sets the lossy quality, 1 to 100, of every image the
+// build encodes: the imageFormats variants and JPEG social images. Unset
+// qualities default to 85 for JPEG, 80 for WebP and 60 for AVIF.
+type imageQualityConfig struct {
+	JPEG int `yaml:"jpeg"`
+	WebP int `yaml:"webp"`
+	AVIF int `yaml:"avif"`
+	// KeepMetadata carries EXIF and XMP metadata over to the variants.
+	// It is dropped by default, which also keeps camera locations out of
+	// published images. Generated social images have none either way.
+	KeepMetadata bool `yaml:"keepMetadata"`
+}
+
+// externalLinksConfig controls links in posts to other sites.
+type externalLinksConfig struct {
+	// NewTab opens them in a new tab with target="_blank".
+	NewTab bool `yaml:"newTab"`
+	// Rel is their rel attribute. Defaults to "noopener noreferrer".
+	Rel string `yaml:"rel"`
+}
+
+// feedsConfig controls the entries of each feed. Zero limits mean no limit.
+type feedsConfig struct {
+	// Path is the site-relative URL of the canonical feed, the one
+	// advertised through autodiscovery. Defaults to "/feed.xml".
+	Path string `yaml:"path"`
+	// LegacyPaths are former feed URLs. Each gets a copy of the canonical
+	// feed whose self link points to Path, so readers that follow it
+	// migrate.
+	LegacyPaths []string `yaml:"legacyPaths"`
+	// RecentLimit caps the canonical feed. Defaults to 10.
+	RecentLimit int `yaml:"recentLimit"`
+	// ArchiveLimit caps feed-all.xml, which has every post by default.
+	ArchiveLimit int `yaml:"archiveLimit"`
+	// Order is "published" (default) to list entries by date, "updated"
+	// to list them by their last modification, or "firstPublished" to
+	// list them by when the build first published them; see
+	// Post.FirstPublished. Other outputs are not affected.
+	Order string `yaml:"order"`
+	// UpdatesDays writes /updates.xml with the posts revised within this
+	// many days of the build, most recently updated first. Zero (default)
+	// writes no updates feed.
+	UpdatesDays int `yaml:"updatesDays"`
+	// Updated is what a feed's <updated> says: "posts" (default), the
+	// latest date or update date of its entries, or "now", the build
+	// time.
+	Updated string `yaml:"updated"`
+	// Links is "absolute" (default) to resolve relative URLs in entry
+	// content against the post's page, since feed readers show it
+	// elsewhere, or "keep" to leave them as written. See absolutizeLinks.
+	Links string `yaml:"links"`
+}
+
+// permissionsConfig sets the modes of everything written to public. Unset
+// modes keep the defaults: generated files follow os.Create and the umask,
+// copied static files are 0644 and directories 0755.
+type permissionsConfig struct {
+	File octalMode `yaml:"file"`
+	Dir  octalMode `yaml:"dir"`
+}
+
+// octalMode is a file mode written in octal, like 0644 or 0o644.
+type octalMode os.FileMode
+
+func (m *octalMode) UnmarshalYAML(value *yaml.Node) error {
+	s := strings.TrimPrefix(strings.TrimPrefix(value.Value, "0o"), "0O")
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode == 0 || mode > 0o777 {
+		return fmt.Errorf("invalid permission %q, want an octal mode like 0644", value.Value)
+	}
+	*m = octalMode(mode)
+	return nil
+}
+
+// sitemapConfig controls the <priority> values in sitemap.xml.
+type sitemapConfig struct {
+	// HomepagePriority defaults to 1.0.
+	HomepagePriority float64 `yaml:"homepagePriority"`
+	// PriorityTiers assign a priority by post age. The first tier whose
+	// MaxAgeDays the post falls within wins; a tier without MaxAgeDays
+	// matches every remaining post and must come last. Defaults to
+	// defaultPriorityTiers.
+	PriorityTiers []priorityTier `yaml:"priorityTiers"`
+	// Text also writes sitemap.txt, the same URLs one per line.
+	Text bool `yaml:"text"`
+}
+
+type priorityTier struct {
+	MaxAgeDays int     `yaml:"maxAgeDays"`
+	Priority   float64 `yaml:"priority"`
+}
+
+var defaultPriorityTiers = []priorityTier{
+	{MaxAgeDays: 30, Priority: 0.8},
+	{MaxAgeDays: 365, Priority: 0.6},
+	{Priority: 0.4},
+}
+
+// readingTimeConfig controls how Post.ReadingTimeString turns a word count
+// into minutes.
+type readingTimeConfig struct {
+	// WordsPerMinute defaults to 200.
+	WordsPerMinute int `yaml:"wordsPerMinute"`
+	// Minimum is the smallest number of minutes ever shown. Defaults to 1.
+	Minimum int `yaml:"minimum"`
+	// Rounding is "ceil" (default) or "round".
+	Rounding string `yaml:"rounding"`
+	// LessThanMinuteLabel, when set, replaces the estimate for posts that
+	// take under a minute to read.
+	LessThanMinuteLabel string `yaml:"lessThanMinuteLabel"`
+}
+
+var cfg siteConfig
+
+func loadConfig(path string) (siteConfig, error) {
+	// Qualities are preset rather than defaulted after decoding, where an
+	// explicit 0 would be indistinguishable from an unset one.
+	c := siteConfig{ImageQuality: imageQualityConfig{JPEG: 85, WebP: 80, AVIF: 60}}
+
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return c, fmt.Errorf("read config %s: %w", path, err)
+	}
+
+	if err := yaml.Unmarshal(content, &c); err != nil {
+		return c, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+
+	if c.SiteURL == "" {
+		c.SiteURL = defaultSiteURL
+	}
+	c.SiteURL = strings.TrimSuffix(c.SiteURL, "/")
+	c.AlternateSiteURL = strings.TrimSuffix(c.AlternateSiteURL, "/")
+	if c.SocialImages.BackgroundColor == "" {
+		c.SocialImages.BackgroundColor = "#f8f5f2"
+	}
+	if c.SocialImages.TextColor == "" {
+		c.SocialImages.TextColor = "#232323"
+	}
+	if c.QRCodes.Recovery == "" {
+		c.QRCodes.Recovery = "medium"
+	}
+	if c.QRCodes.ModuleSize == 0 {
+		c.QRCodes.ModuleSize = 8
+	}
+	if c.SocialImages.Format == "" {
+		c.SocialImages.Format = "png"
+	}
+	if c.Descriptions.MinLength == 0 {
+		c.Descriptions.MinLength = 50
+	}
+	if c.Descriptions.MaxLength == 0 {
+		c.Descriptions.MaxLength = 160
+	}
+	if c.BuildInfo.Fields == nil {
+		c.BuildInfo.Fields = buildInfoFields
+	}
+	if c.ExternalLinks.Rel == "" {
+		c.ExternalLinks.Rel = "noopener noreferrer"
+	}
+	if c.PrintStylesheet == "" {
+		c.PrintStylesheet = "/print.css"
+	}
+	if c.Language == "" {
+		c.Language = "en"
+	}
+	if c.TimeLayout == "" {
+		c.TimeLayout = "15:04"
+	}
+	c.location = time.UTC
+	if c.Timezone != "" {
+		c.location, err = time.LoadLocation(c.Timezone)
+		if err != nil {
+			return c, fmt.Errorf("invalid config %s: timezone: %w", path, err)
+		}
+	}
+	if c.Feeds.Path == "" {
+		c.Feeds.Path = "/feed.xml"
+	}
+	if c.Feeds.RecentLimit == 0 {
+		c.Feeds.RecentLimit = 10
+	}
+	if c.Sitemap.HomepagePriority == 0 {
+		c.Sitemap.HomepagePriority = 1.0
+	}
+	if c.ContentTypes == nil {
+		c.ContentTypes = defaultContentTypes
+	}
+	for i := range c.ContentTypes {
+		if p := c.ContentTypes[i].URLPrefix; p != "" && !strings.HasSuffix(p, "/") {
+			c.ContentTypes[i].URLPrefix = p + "/"
+		}
+	}
+	if c.Sitemap.PriorityTiers == nil {
+		c.Sitemap.PriorityTiers = defaultPriorityTiers
+	}
+	if c.Author.Name == "" {
+		c.Author.Name = defaultAuthor
+	}
+	if c.Taxonomies == nil {
+		c.Taxonomies = defaultTaxonomies
+	}
+	for i := range c.Taxonomies {
+		tx := &c.Taxonomies[i]
+		if tx.URLPrefix == "" {
+			tx.URLPrefix = tx.Name
+		}
+		if !strings.HasSuffix(tx.URLPrefix, "/") {
+			tx.URLPrefix += "/"
+		}
+		if tx.Template == "" {
+			tx.Template = "taxonomy.gohtml"
+		}
+	}
+
+	if err := c.validate(); err != nil {
+		return c, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return c, nil
+}
+
+func (c siteConfig) validate() error {
+	if err := validateSiteURL(c.SiteURL); err != nil {
+		return fmt.Errorf("siteURL: %w", err)
+	}
+	if c.AlternateSiteURL != "" {
+		if err := validateSiteURL(c.AlternateSiteURL); err != nil {
+			return fmt.Errorf("alternateSiteURL: %w", err)
+		}
+		if c.AlternateSiteURL == c.SiteURL {
+			return errors.New("alternateSiteURL must differ from siteURL")
+		}
+	}
+	if c.ReadingTime.WordsPerMinute < 0 {
+		return errors.New("readingTime.wordsPerMinute must be positive")
+	}
+	if c.ReadingTime.Minimum < 0 {
+		return errors.New("readingTime.minimum must not be negative")
+	}
+	if c.SearchURLTemplate != "" && !strings.Contains(c.SearchURLTemplate, "{search_term_string}") {
+		return errors.New("searchURLTemplate must contain {search_term_string}")
+	}
+	for _, format := range c.ImageFormats {
+		if _, ok := imageEncoders[format]; !ok {
+			return fmt.Errorf("imageFormats: unsupported format %q", format)
+		}
+	}
+	for name, q := range map[string]int{"jpeg": c.ImageQuality.JPEG, "webp": c.ImageQuality.WebP, "avif": c.ImageQuality.AVIF} {
+		if q < 1 || q > 100 {
+			return fmt.Errorf("imageQuality.%s must be between 1 and 100, got %d", name, q)
+		}
+	}
+	if _, ok := qrRecoveryLevels[c.QRCodes.Recovery]; !ok {
+		return fmt.Errorf("qrCodes.recovery must be low, medium, high or highest, got %q", c.QRCodes.Recovery)
+	}
+	if c.QRCodes.ModuleSize < 0 {
+		return errors.New("qrCodes.moduleSize must not be negative")
+	}
+	if c.Descriptions.MinLength < 0 {
+		return errors.New("descriptions.minLength must not be negative")
+	}
+	if c.Descriptions.MaxLength < c.Descriptions.MinLength {
+		return fmt.Errorf("descriptions.maxLength must not be below minLength %d, got %d", c.Descriptions.MinLength, c.Descriptions.MaxLength)
+	}
+	for _, f := range c.BuildInfo.Fields {
+		if !slices.Contains(buildInfoFields, f) {
+			return fmt.Errorf("buildInfo.fields must be time, commit or hash, got %q", f)
+		}
+	}
+	if f := c.SocialImages.Format; f != "png" && f != "jpeg" {
+		return fmt.Errorf("socialImages.format must be png or jpeg, got %q", f)
+	}
+	if err := validatePriority(c.Sitemap.HomepagePriority); err != nil {
+		return fmt.Errorf("sitemap.homepagePriority: %w", err)
+	}
+	prevAge := 0
+	for i, tier := range c.Sitemap.PriorityTiers {
+		if err := validatePriority(tier.Priority); err != nil {
+			return fmt.Errorf("sitemap.priorityTiers[%d]: %w", i, err)
+		}
+		if tier.MaxAgeDays < 0 {
+			return fmt.Errorf("sitemap.priorityTiers[%d]: maxAgeDays must not be negative", i)
+		}
+		if tier.MaxAgeDays == 0 && i != len(c.Sitemap.PriorityTiers)-1 {
+			return fmt.Errorf("sitemap.priorityTiers[%d]: only the last tier may omit maxAgeDays", i)
+		}
+		if tier.MaxAgeDays != 0 && tier.MaxAgeDays <= prevAge {
+			return fmt.Errorf("sitemap.priorityTiers[%d]: maxAgeDays must increase", i)
+		}
+		prevAge = tier.MaxAgeDays
+	}
+	if c.Feeds.RecentLimit < 0 || c.Feeds.ArchiveLimit < 0 || c.Feeds.UpdatesDays < 0 {
+		return errors.New("feed limits must not be negative")
+	}
+	if c.StaleAfterYears < 0 {
+		return errors.New("staleAfterYears must not be negative")
+	}
+	switch c.DuplicateTitles {
+	case "", "warn", "error":
+	default:
+		return fmt.Errorf("duplicateTitles must be warn or error, got %q", c.DuplicateTitles)
+	}
+	if c.RelatedPosts < 0 {
+		return errors.New("relatedPosts must not be negative")
+	}
+	if c.SourceEncoding != "" {
+		if _, err := htmlindex.Get(c.SourceEncoding); err != nil {
+			return fmt.Errorf("sourceEncoding: unknown encoding %q", c.SourceEncoding)
+		}
+	}
+	switch c.InvalidUTF8 {
+	case "", "error", "replace":
+	default:
+		return fmt.Errorf("invalidUTF8 must be error or replace, got %q", c.InvalidUTF8)
+	}
+	for _, pattern := range c.ProtectedPaths {
+		if _, err := path.Match(strings.TrimPrefix(pattern, "/"), ""); err != nil {
+			return fmt.Errorf("protectedPaths: invalid pattern %q", pattern)
+		}
+	}
+	switch c.Feeds.Updated {
+	case "", "posts", "now":
+	default:
+		return fmt.Errorf("feeds.updated must be posts or now, got %q", c.Feeds.Updated)
+	}
+	switch c.FeedIDStrategy {
+	case "", "url", "slug", "hash":
+	default:
+		return fmt.Errorf("feedIDStrategy must be url, slug or hash, got %q", c.FeedIDStrategy)
+	}
+	for _, p := range append([]string{c.Feeds.Path}, c.Feeds.LegacyPaths...) {
+		if !strings.HasPrefix(p, "/") || strings.Contains(p, "..") {
+			return fmt.Errorf("feed path %q must start with / and stay inside the site", p)
+		}
+		if p == "/feed-all.xml" {
+			return fmt.Errorf("feed path %q is taken by the archive feed", p)
+		}
+	}
+	for _, p := range c.Feeds.LegacyPaths {
+		if p == c.Feeds.Path {
+			return fmt.Errorf("feeds.legacyPaths: %q is the canonical feed path", p)
+		}
+	}
+	names := map[string]bool{}
+	for i, ct := range c.ContentTypes {
+		if ct.Name == "" || ct.Dir == "" {
+			return fmt.Errorf("contentTypes[%d]: name and dir are required", i)
+		}
+		if names[ct.Name] {
+			return fmt.Errorf("contentTypes[%d]: duplicate name %q", i, ct.Name)
+		}
+		names[ct.Name] = true
+		if strings.HasPrefix(ct.URLPrefix, "/") || strings.Contains(ct.URLPrefix, "..") {
+			return fmt.Errorf("contentTypes[%d]: urlPrefix %q must be relative and stay inside the site", i, ct.URLPrefix)
+		}
+		if ct.Template != "" && htmlTemplates.Lookup(ct.Template) == nil {
+			return fmt.Errorf("contentTypes[%d]: unknown template %q", i, ct.Template)
+		}
+	}
+	if c.EditURLTemplate != "" && !strings.Contains(c.EditURLTemplate, "{filename}") && !strings.Contains(c.EditURLTemplate, "{path}") {
+		return errors.New("editURLTemplate must contain {filename} or {path}")
+	}
+	bundles := map[string]bool{}
+	for i, b := range c.Bundles {
+		if b.Name == "" || len(b.Files) == 0 {
+			return fmt.Errorf("bundles[%d]: name and files are required", i)
+		}
+		if bundles[b.Name] {
+			return fmt.Errorf("bundles[%d]: duplicate name %q", i, b.Name)
+		}
+		bundles[b.Name] = true
+		if strings.HasPrefix(b.Name, "/") || strings.Contains(b.Name, "..") {
+			return fmt.Errorf("bundles[%d]: name %q must be relative and stay inside the site", i, b.Name)
+		}
+		switch path.Ext(b.Name) {
+		case ".css":
+		case ".js":
+			if b.Minify {
+				return fmt.Errorf("bundles[%d]: minify is only supported for CSS bundles", i)
+			}
+		default:
+			return fmt.Errorf("bundles[%d]: name %q must end in .css or .js", i, b.Name)
+		}
+		for _, file := range b.Files {
+			if strings.HasPrefix(file, "/") || strings.Contains(file, "..") {
+				return fmt.Errorf("bundles[%d]: file %q must be a path under static/", i, file)
+			}
+		}
+	}
+	taxonomies := map[string]bool{}
+	for i, tx := range c.Taxonomies {
+		if tx.Name == "" {
+			return fmt.Errorf("taxonomies[%d]: name is required", i)
+		}
+		if taxonomies[tx.Name] {
+			return fmt.Errorf("taxonomies[%d]: duplicate name %q", i, tx.Name)
+		}
+		taxonomies[tx.Name] = true
+		if strings.HasPrefix(tx.URLPrefix, "/") || strings.Contains(tx.URLPrefix, "..") {
+			return fmt.Errorf("taxonomies[%d]: urlPrefix %q must be relative and stay inside the site", i, tx.URLPrefix)
+		}
+		if htmlTemplates.Lookup(tx.Template) == nil {
+			return fmt.Errorf("taxonomies[%d]: unknown template %q", i, tx.Template)
+		}
+	}
+	if c.Series.Taxonomy != "" && !taxonomies[c.Series.Taxonomy] {
+		return fmt.Errorf("series.taxonomy must name one of taxonomies, got %q", c.Series.Taxonomy)
+	}
+	switch c.Feeds.Links {
+	case "", "absolute", "keep":
+	default:
+		return fmt.Errorf("feeds.links must be absolute or keep, got %q", c.Feeds.Links)
+	}
+	switch c.Feeds.Order {
+	case "", "published", "updated", "firstPublished":
+	default:
+		return fmt.Errorf("feeds.order must be published, updated or firstPublished, got %q", c.Feeds.Order)
+	}
+	switch c.RawHTML {
+	case "", "omit", "unsafe", "escape", "sanitize":
+	default:
+		return fmt.Errorf("rawHTML must be omit, unsafe, escape or sanitize, got %q", c.RawHTML)
+	}
+	switch c.UnicodeSlugs {
+	case "", "keep", "strip", "percent":
+	default:
+		return fmt.Errorf("unicodeSlugs must be keep, strip or percent, got %q", c.UnicodeSlugs)
+	}
+	switch c.ReadingTime.Rounding {
+	case "", "ceil", "round":
+	default:
+		return fmt.Errorf("readingTime.rounding must be ceil or round, got %q", c.ReadingTime.Rounding)
+	}
+	return nil
+}
+
+// validateSiteURL checks that raw is an absolute http(s) origin with an
+// optional path and nothing else.
+func validateSiteURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%q must use http or https", raw)
+	}
+	if u.Host == "" {
+		return fmt.Errorf("%q has no host", raw)
+	}
+	if u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+		return fmt.Errorf("%q must not contain credentials, a query or a fragment", raw)
+	}
+	return nil
+}
+
+func validatePriority(p float64) error {
+	if p < 0 || p > 1 {
+		return fmt.Errorf("priority %v must be between 0.0 and 1.0", p)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImageQuality(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		want    imageQualityConfig
+		wantErr bool
+	}{
+		{"unset", "", imageQualityConfig{JPEG: 85, WebP: 80, AVIF: 60}, false},
+		{"partly set", "imageQuality:\n  webp: 90\n", imageQualityConfig{JPEG: 85, WebP: 90, AVIF: 60}, false},
+		{"bounds", "imageQuality:\n  jpeg: 1\n  webp: 100\n", imageQualityConfig{JPEG: 1, WebP: 100, AVIF: 60}, false},
+		{"explicit zero", "imageQuality:\n  jpeg: 0\n", imageQualityConfig{}, true},
+		{"above 100", "imageQuality:\n  avif: 101\n", imageQualityConfig{}, true},
+		{"negative", "imageQuality:\n  webp: -5\n", imageQualityConfig{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), configPath)
+			if err := os.WriteFile(path, []byte(tt.config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			c, err := loadConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadConfig error = %v, want error: %v", err, tt.wantErr)
+			}
+			if err == nil && c.ImageQuality != tt.want {
+				t.Errorf("imageQuality = %+v, want %+v", c.ImageQuality, tt.want)
+			}
+		})
+	}
+}
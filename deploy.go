@@ -0,0 +1,23 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+// runDeploy runs cfg.DeployCommand through the shell with dir in
+// $OUTPUT_DIR, passing its output through. A failing command is returned
+// as an *exec.ExitError so its exit code can be surfaced.
+func runDeploy(dir string) error {
+	if cfg.DeployCommand == "" {
+		return errors.New("no deployCommand in " + configPath)
+	}
+
+	cmd := exec.Command("sh", "-c", cfg.DeployCommand)
+	cmd.Env = append(os.Environ(), "OUTPUT_DIR="+dir)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var kindDiagram = ast.NewNodeKind("Diagram")
+
+// diagramNode is a ```mermaid or ```math fence, rendered for the client
+// side Mermaid and KaTeX scripts instead of being highlighted as code.
+type diagramNode struct {
+	ast.BaseBlock
+	lang string
+}
+
+func (n *diagramNode) Kind() ast.NodeKind { return kindDiagram }
+
+func (n *diagramNode) IsRaw() bool { return true }
+
+func (n *diagramNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Lang": n.lang}, nil)
+}
+
+// diagramTransformer replaces mermaid and math fences with diagramNodes.
+type diagramTransformer struct{}
+
+func (diagramTransformer) Transform(doc *ast.Document, reader text.Reader, _ parser.Context) {
+	var fences []*ast.FencedCodeBlock
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if f, ok := n.(*ast.FencedCodeBlock); ok && entering {
+			switch string(f.Language(reader.Source())) {
+			case "mermaid", "math":
+				fences = append(fences, f)
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, f := range fences {
+		d := &diagramNode{lang: string(f.Language(reader.Source()))}
+		d.SetLines(f.Lines())
+		f.Parent().ReplaceChild(f.Parent(), f, d)
+	}
+}
+
+type diagramRenderer struct{}
+
+func (diagramRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindDiagram, func(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		n := node.(*diagramNode)
+		if n.lang == "mermaid" {
+			_, _ = w.WriteString(`<pre class="mermaid">`)
+		} else {
+			_, _ = w.WriteString("<div class=\"math\">$$\n")
+		}
+		lines := n.Lines()
+		for i := 0; i < lines.Len(); i++ {
+			seg := lines.At(i)
+			_, _ = w.Write(util.EscapeHTML(seg.Value(source)))
+		}
+		if n.lang == "mermaid" {
+			_, _ = w.WriteString("</pre>\n")
+		} else {
+			_, _ = w.WriteString("$$</div>\n")
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+// contentFeatures reports what client-side assets a post needs: math in
+// math fences or between $$ delimiters in a paragraph's text, and Mermaid
+// diagrams. Code blocks and spans never count, even when they show $$.
+func contentFeatures(doc ast.Node, source []byte) (hasMath, hasMermaid bool) {
+	// dollars counts the $$ in the text of the paragraph being walked.
+	dollars := 0
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		switch n := n.(type) {
+		case *diagramNode:
+			if entering {
+				hasMath = hasMath || n.lang == "math"
+				hasMermaid = hasMermaid || n.lang == "mermaid"
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.FencedCodeBlock, *ast.CodeBlock, *ast.CodeSpan:
+			return ast.WalkSkipChildren, nil
+		case *ast.Paragraph:
+			if entering {
+				dollars = 0
+			} else if dollars >= 2 {
+				hasMath = true
+			}
+		case *ast.Text:
+			if entering {
+				dollars += strings.Count(string(n.Segment.Value(source)), "$$")
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return hasMath, hasMermaid
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/yuin/goldmark/text"
+)
+
+func TestContentFeatures(t *testing.T) {
+	const (
+		mathFence    = "```math\na^2 + b^2 = c^2\n```\n"
+		mermaidFence = "```mermaid\ngraph TD; A-->B\n```\n"
+		goFence      = "```go\nfmt.Println(\"$$ $$\")\n```\n"
+	)
+	tests := []struct {
+		name          string
+		source        string
+		math, mermaid bool
+	}{
+		{"none", "Just *text* and a [link](/x).\n", false, false},
+		{"code only", goFence + "\nUse `$$x$$` for display math.\n", false, false},
+		{"math fence", mathFence, true, false},
+		{"dollar math", "Euler: $$e^{i\\pi} + 1 = 0$$\n", true, false},
+		{"dollar math over lines", "$$\na = b\n$$\n", true, false},
+		{"single dollars", "It costs $$5, or $5.\n", false, false},
+		{"dollars split across paragraphs", "From $$ here.\n\nTo $$ there.\n", false, false},
+		{"mermaid", mermaidFence, false, true},
+		{"all three", mathFence + "\n" + mermaidFence + "\n" + goFence + "\nAnd `inline` code.\n", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := []byte(tt.source)
+			doc := md.Parser().Parse(text.NewReader(source))
+			math, mermaid := contentFeatures(doc, source)
+			if math != tt.math || mermaid != tt.mermaid {
+				t.Errorf("contentFeatures = math %v, mermaid %v, want %v, %v", math, mermaid, tt.math, tt.mermaid)
+			}
+		})
+	}
+}
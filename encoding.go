@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// decodeContent returns a post's content as UTF-8, transcoding it from
+// cfg.SourceEncoding first when one is set. By default, invalid UTF-8 is
+// an error naming the first bad byte and its offset; callers add the
+// filename. Only with cfg.InvalidUTF8 set to "replace" is the content
+// repaired, with a warning naming filename: bytes.ToValidUTF8 replaces
+// each run of invalid bytes with a single U+FFFD. A leading byte order
+// mark is dropped, so that front matter still opens the file; offsets
+// count it.
+func decodeContent(filename string, content []byte) ([]byte, error) {
+	content, err := transcode(content)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := invalidUTF8Offset(content)
+	if offset < 0 {
+		return bytes.TrimPrefix(content, byteOrderMark), nil
+	}
+	if cfg.InvalidUTF8 != "replace" {
+		return nil, fmt.Errorf("not valid UTF-8: invalid byte 0x%02x at offset %d", content[offset], offset)
+	}
+	warnf("%s is not valid UTF-8: invalid byte 0x%02x at offset %d, replacing invalid bytes with U+FFFD", filename, content[offset], offset)
+	return bytes.TrimPrefix(bytes.ToValidUTF8(content, []byte("�")), byteOrderMark), nil
+}
+
+// byteOrderMark is U+FEFF encoded as UTF-8, which some editors start files
+// with.
+var byteOrderMark = []byte("\xef\xbb\xbf")
+
+// transcode converts content from cfg.SourceEncoding to UTF-8.
+func transcode(content []byte) ([]byte, error) {
+	if cfg.SourceEncoding == "" {
+		return content, nil
+	}
+	enc, err := htmlindex.Get(cfg.SourceEncoding)
+	if err != nil {
+		return nil, err
+	}
+	content, err = enc.NewDecoder().Bytes(content)
+	if err != nil {
+		return nil, fmt.Errorf("decode from %s: %w", cfg.SourceEncoding, err)
+	}
+	return content, nil
+}
+
+// invalidUTF8Offset returns the offset of the first byte of content that
+// is not part of valid UTF-8, or -1 if there is none.
+func invalidUTF8Offset(content []byte) int {
+	for i := 0; i < len(content); {
+		r, size := utf8.DecodeRune(content[i:])
+		if r == utf8.RuneError && size == 1 {
+			return i
+		}
+		i += size
+	}
+	return -1
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDecodeContent(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		invalidUTF8 string
+		encoding    string
+		want        string
+		wantErr     string
+		warn        bool
+	}{
+		{"valid", "café", "", "", "café", "", false},
+		{"byte order mark", "\xef\xbb\xbf---\n", "", "", "---\n", "", false},
+		{"invalid", "ab\xffcd", "", "", "", "invalid byte 0xff at offset 2", false},
+		{"truncated at end", "caf\xc3", "", "", "", "invalid byte 0xc3 at offset 3", false},
+		{"offset counts byte order mark", "\xef\xbb\xbfa\xfe", "", "", "", "invalid byte 0xfe at offset 4", false},
+		{"replaced", "ab\xff\xfecd", "replace", "", "ab�cd", "", true},
+		{"replaced after byte order mark", "\xef\xbb\xbfa\xff", "replace", "", "a�", "", true},
+		{"transcoded", "caf\xe9", "", "windows-1252", "café", "", false},
+	}
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.InvalidUTF8 = tt.invalidUTF8
+			cfg.SourceEncoding = tt.encoding
+			before := warnings
+			got, err := decodeContent("x.md", []byte(tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("decodeContent(%q) error = %v, want %q", tt.content, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || string(got) != tt.want {
+				t.Errorf("decodeContent(%q) = %q, %v, want %q", tt.content, got, err, tt.want)
+			}
+			if warned := warnings > before; warned != tt.warn {
+				t.Errorf("warned: %v, want %v", warned, tt.warn)
+			}
+		})
+	}
+}
+
+func TestByteOrderMarkFrontMatter(t *testing.T) {
+	useDefaultConfig(t)
+	post, err := parsePost(cfg.ContentTypes[0], "x.md", []byte("\xef\xbb\xbf---\ntitle: Marked\ndate: 2024-01-01\n---\n\nBody.\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post.Title != "Marked" || strings.Contains(string(post.Content), "\uFEFF") {
+		t.Errorf("post = %q with content %q, want title Marked and no byte order mark", post.Title, post.Content)
+	}
+}
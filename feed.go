@@ -0,0 +1,278 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"time"
+)
+
+const siteTitle = "Özgür Tanrıverdi (otrv)"
+
+// feedFile is one Atom feed written to public.
+type feedFile struct {
+	// Path is the site-relative URL of the feed, e.g. "/feed.xml".
+	Path  string
+	Title string
+	Posts []Post
+	// LegacyPaths are old URLs of the feed that keep being written, with
+	// their self link pointing at Path.
+	LegacyPaths []string
+}
+
+type FeedData struct {
+	// Path is the feed's canonical URL, which a Legacy copy points to.
+	Path    string
+	Legacy  bool
+	Title   string
+	Updated string
+	// Author is the feed-level author, cfg.Author.
+	Author Author
+	Posts  []Post
+}
+
+// siteFeeds lists every feed the site publishes. The first one is the
+// primary feed advertised through autodiscovery links.
+func siteFeeds(posts []Post) []feedFile {
+	posts = feedOrder(posts)
+	return []feedFile{
+		{Path: cfg.Feeds.Path, Title: siteTitle, Posts: limitPosts(posts, cfg.Feeds.RecentLimit), LegacyPaths: cfg.Feeds.LegacyPaths},
+		{Path: "/feed-all.xml", Title: siteTitle + " – all posts", Posts: limitPosts(posts, cfg.Feeds.ArchiveLimit)},
+	}
+}
+
+// yearlyFeeds lists a feed per year at /YYYY/atom.xml with every post
+// dated in it, newest year first. Years without posts get none.
+func yearlyFeeds(posts []Post) []feedFile {
+	var years []int
+	byYear := map[int][]Post{}
+	for _, post := range posts {
+		year := post.Date.Year()
+		if byYear[year] == nil {
+			years = append(years, year)
+		}
+		byYear[year] = append(byYear[year], post)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(years)))
+
+	feeds := make([]feedFile, 0, len(years))
+	for _, year := range years {
+		feeds = append(feeds, feedFile{
+			Path:  fmt.Sprintf("/%d/atom.xml", year),
+			Title: fmt.Sprintf("%s – %d", siteTitle, year),
+			Posts: feedOrder(byYear[year]),
+		})
+	}
+	return feeds
+}
+
+// updatesPath is where the updates feed is written.
+const updatesPath = "/updates.xml"
+
+// updatedPosts returns the posts revised within cfg.Feeds.UpdatesDays of
+// the build, most recently updated first. Posts without an updated date
+// are new rather than revised and are left to the other feeds.
+func updatedPosts(posts []Post) []Post {
+	since := buildTime.AddDate(0, 0, -cfg.Feeds.UpdatesDays)
+	updated := postsWhere(posts, func(p Post) bool {
+		return p.Updated.After(p.Date) && !p.Updated.Before(since)
+	})
+	sort.SliceStable(updated, func(i, j int) bool {
+		return updated[i].Updated.After(updated[j].Updated)
+	})
+	return updated
+}
+
+// generateUpdatesFeed writes the updates feed when cfg.Feeds.UpdatesDays
+// is set. It is written even with no recent revisions, so subscribers
+// keep a valid feed.
+func generateUpdatesFeed(posts []Post) error {
+	if cfg.Feeds.UpdatesDays == 0 || len(posts) == 0 {
+		return nil
+	}
+	// Without recent revisions the feed stays as of the newest post, not
+	// the build, so unchanged rebuilds do not touch it.
+	updated := updatedPosts(posts)
+	last := feedUpdated(updated, feedUpdated(posts, time.Unix(0, 0).UTC()))
+
+	path := filepath.Join("public", filepath.FromSlash(updatesPath))
+	f, err := createOutput(path)
+	if err != nil {
+		return fmt.Errorf("create feed %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if err := updatesTmpl.ExecuteTemplate(f, "updates.xml", FeedData{
+		Path:    updatesPath,
+		Title:   siteTitle + " – updates",
+		Updated: last.Format(time.RFC3339),
+		Author:  cfg.Author,
+		Posts:   updated,
+	}); err != nil {
+		return fmt.Errorf("render feed %s: %w", path, err)
+	}
+	return nil
+}
+
+// feedOrder returns posts in the order of cfg.Feeds.Order. Posts come
+// newest-published first; "updated" and "firstPublished" reorder a copy by
+// feedDate, so a revised or newly published post resurfaces in the feeds
+// without moving anywhere else.
+func feedOrder(posts []Post) []Post {
+	if cfg.Feeds.Order != "updated" && cfg.Feeds.Order != "firstPublished" {
+		return posts
+	}
+	ordered := slices.Clone(posts)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return feedDate(ordered[i]).After(feedDate(ordered[j]))
+	})
+	return ordered
+}
+
+// feedDate is the time feeds are ordered by under cfg.Feeds.Order.
+func feedDate(p Post) time.Time {
+	switch cfg.Feeds.Order {
+	case "updated":
+		return p.LastModified()
+	case "firstPublished":
+		if !p.FirstPublished.IsZero() {
+			return p.FirstPublished
+		}
+	}
+	return p.Date
+}
+
+// limitPosts returns at most limit posts; a limit of zero means all.
+func limitPosts(posts []Post, limit int) []Post {
+	if limit > 0 && len(posts) > limit {
+		return posts[:limit]
+	}
+	return posts
+}
+
+// generateFeeds writes every feed. Until there are posts to list, there
+// are no feeds at all rather than empty ones.
+func generateFeeds(posts []Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	for _, feed := range append(siteFeeds(posts), yearlyFeeds(posts)...) {
+		if err := writeFeed(feed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// feedUpdated is the <updated> time of a feed of posts: the latest date,
+// update date or feedDate among them, so rebuilding without changes
+// writes the same feed. Without posts it is fallback. With
+// cfg.Feeds.Updated set to "now" it is the build time instead.
+func feedUpdated(posts []Post, fallback time.Time) time.Time {
+	if cfg.Feeds.Updated == "now" {
+		return buildTime
+	}
+	if len(posts) == 0 {
+		return fallback
+	}
+	var latest time.Time
+	for _, p := range posts {
+		for _, t := range []time.Time{p.LastModified(), feedDate(p)} {
+			if t.After(latest) {
+				latest = t
+			}
+		}
+	}
+	return latest
+}
+
+func writeFeed(feed feedFile) error {
+	data := FeedData{
+		Path:    feed.Path,
+		Title:   feed.Title,
+		Updated: feedUpdated(feed.Posts, time.Unix(0, 0).UTC()).Format(time.RFC3339),
+		Author:  cfg.Author,
+		Posts:   feed.Posts,
+	}
+	if err := renderFeed(feed.Path, data); err != nil {
+		return err
+	}
+
+	data.Legacy = true
+	for _, legacy := range feed.LegacyPaths {
+		if err := renderFeed(legacy, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renderFeed writes data as the feed served at the site-relative urlPath.
+func renderFeed(urlPath string, data FeedData) error {
+	path := filepath.Join("public", filepath.FromSlash(urlPath))
+	if err := mkdirOutput(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("create feed dir for %s: %w", path, err)
+	}
+	f, err := createOutput(path)
+	if err != nil {
+		return fmt.Errorf("create feed %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if err := feedTmpl.ExecuteTemplate(f, "feed.xml", data); err != nil {
+		return fmt.Errorf("render feed %s: %w", path, err)
+	}
+	return nil
+}
+
+// OPMLOutline is an OPML outline: either a feed or a group of outlines.
+type OPMLOutline struct {
+	Text     string
+	XMLURL   string
+	Outlines []OPMLOutline
+}
+
+type OPMLData struct {
+	Title    string
+	Outlines []OPMLOutline
+}
+
+// generateOPML writes feeds.opml so readers can subscribe to every feed
+// at once. Like the feeds, it is not written until there are posts.
+func generateOPML(posts []Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	f, err := createOutput("public/feeds.opml")
+	if err != nil {
+		return fmt.Errorf("create opml: %w", err)
+	}
+	defer f.Close()
+
+	var outlines []OPMLOutline
+	for _, feed := range siteFeeds(posts) {
+		outlines = append(outlines, OPMLOutline{Text: feed.Title, XMLURL: cfg.SiteURL + feed.Path})
+	}
+	if cfg.Feeds.UpdatesDays > 0 {
+		outlines = append(outlines, OPMLOutline{Text: siteTitle + " – updates", XMLURL: cfg.SiteURL + updatesPath})
+	}
+	if tx, ok := seriesTaxonomy(); ok && len(seriesPosts(posts)) > 0 {
+		outlines = append(outlines, OPMLOutline{Text: siteTitle + " – " + tx.Name, XMLURL: cfg.SiteURL + seriesFeedPath(tx)})
+	}
+	if feeds := yearlyFeeds(posts); len(feeds) > 0 {
+		yearly := OPMLOutline{Text: "yearly"}
+		for _, feed := range feeds {
+			yearly.Outlines = append(yearly.Outlines, OPMLOutline{Text: feed.Title, XMLURL: cfg.SiteURL + feed.Path})
+		}
+		outlines = append(outlines, yearly)
+	}
+
+	if err := opmlTmpl.ExecuteTemplate(f, "feeds.opml", OPMLData{
+		Title:    siteTitle,
+		Outlines: outlines,
+	}); err != nil {
+		return fmt.Errorf("render opml: %w", err)
+	}
+	return nil
+}
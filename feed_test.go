@@ -0,0 +1,176 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFeedOrder(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	// Newest published first, as build sorts them.
+	posts := []Post{
+		{Title: "c", Date: day(3)},
+		{Title: "b", Date: day(2), Updated: day(9)},
+		{Title: "a", Date: day(1), FirstPublished: day(5)},
+	}
+	tests := []struct {
+		order string
+		want  []string
+	}{
+		{"", []string{"c", "b", "a"}},
+		{"published", []string{"c", "b", "a"}},
+		{"updated", []string{"b", "c", "a"}},
+		{"firstPublished", []string{"a", "c", "b"}},
+	}
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			cfg.Feeds.Order = tt.order
+			var got []string
+			for _, p := range feedOrder(posts) {
+				got = append(got, p.Title)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("feedOrder = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestFeedListings checks that feeds, term feeds included, leave out posts
+// with feed: false and keep to dates where the index pins posts first.
+func TestFeedListings(t *testing.T) {
+	buildSite(t, "taxonomies:\n  - name: tags\n    feed: true\n", map[string]string{
+		"old":      "date: 2024-01-01\npinned: true",
+		"new":      "date: 2024-03-01",
+		"unfeeded": "date: 2024-02-01\nfeed: false",
+	})
+	tests := []struct {
+		output string
+		want   []string
+	}{
+		{"index.html", []string{"old", "new", "unfeeded"}},
+		{"all.html", []string{"old", "unfeeded", "new"}},
+		{"tags/shared.html", []string{"new", "unfeeded", "old"}},
+		{"feed.xml", []string{"new", "old"}},
+		{"tags/shared.xml", []string{"new", "old"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			content, err := os.ReadFile("public/" + tt.output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := listedSlugs(string(content), "old", "new", "unfeeded"); !slices.Equal(got, tt.want) {
+				t.Errorf("%s lists %v, want %v", tt.output, got, tt.want)
+			}
+		})
+	}
+}
+
+// listedSlugs returns the slugs content links to, in the order of their
+// first link.
+func listedSlugs(content string, slugs ...string) []string {
+	var listed []string
+	for _, slug := range slugs {
+		if strings.Contains(content, "/"+slug+".html") {
+			listed = append(listed, slug)
+		}
+	}
+	slices.SortFunc(listed, func(a, b string) int {
+		return strings.Index(content, "/"+a+".html") - strings.Index(content, "/"+b+".html")
+	})
+	return listed
+}
+
+// TestUpdateResurfaces checks that with feeds ordered by update, an old
+// post's update takes it to the top of the feed but leaves the index and
+// sitemap in publish order.
+func TestUpdateResurfaces(t *testing.T) {
+	buildSite(t, "feeds:\n  order: updated\n", map[string]string{
+		"old":    "date: 2024-01-01\nupdated: 2024-06-01",
+		"middle": "date: 2024-02-01",
+		"new":    "date: 2024-03-01",
+	})
+	tests := []struct {
+		output string
+		want   []string
+	}{
+		{"feed.xml", []string{"old", "new", "middle"}},
+		{"index.html", []string{"new", "middle", "old"}},
+		{"sitemap.xml", []string{"new", "middle", "old"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.output, func(t *testing.T) {
+			content, err := os.ReadFile("public/" + tt.output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := listedSlugs(string(content), "old", "middle", "new"); !slices.Equal(got, tt.want) {
+				t.Errorf("%s lists %v, want %v", tt.output, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeedAuthors(t *testing.T) {
+	tests := []struct {
+		name string
+		meta string
+		want []string
+		warn bool
+	}{
+		{"explicit author", "author: ann", []string{"Ann"}, false},
+		{"explicit authors", "author: ann\nauthors: [bob]", []string{"Ann", "Bob"}, false},
+		{"default", "", []string{"Site"}, false},
+		{"missing", "author: nobody", []string{"Site"}, true},
+		{"partly missing", "authors: [nobody, bob]", []string{"Bob"}, true},
+	}
+	useDefaultConfig(t)
+	cfg.Author = Author{Name: "Site"}
+	saved := authors
+	t.Cleanup(func() { authors = saved })
+	authors = map[string]Author{"ann": {Key: "ann", Name: "Ann"}, "bob": {Key: "bob", Name: "Bob"}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := warnings
+			post, err := parsePost(cfg.ContentTypes[0], "x.md", []byte("---\ntitle: x\ndate: 2024-01-01\n"+tt.meta+"\n---\n\nBody.\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, a := range post.FeedAuthors() {
+				got = append(got, a.Name)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("FeedAuthors = %v, want %v", got, tt.want)
+			}
+			if warned := warnings > before; warned != tt.warn {
+				t.Errorf("warned: %v, want %v", warned, tt.warn)
+			}
+		})
+	}
+}
+
+// TestFeedAuthorElements checks that the site's author, with its email and
+// URL, is the author of the feed and of entries without one.
+func TestFeedAuthorElements(t *testing.T) {
+	buildSite(t, "author:\n  name: Site\n  email: site@example.com\n  url: https://example.com/\n", map[string]string{
+		"post": "date: 2024-01-01",
+	})
+	feed, err := os.ReadFile("public/feed.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	author := "<author>\n      <name>Site</name>\n      <email>site@example.com</email>\n      <uri>https://example.com/</uri>\n    </author>"
+	if !strings.Contains(string(feed), author) {
+		t.Errorf("feed.xml entry has no site author:\n%s", feed)
+	}
+	if strings.Count(string(feed), "<name>Site</name>") != 2 {
+		t.Errorf("feed.xml does not name the site author for the feed and its entry:\n%s", feed)
+	}
+}
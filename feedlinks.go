@@ -0,0 +1,82 @@
+package main
+
+import (
+	"html"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// urlAttrPattern matches the URL-valued attributes of rendered content.
+var urlAttrPattern = regexp.MustCompile(`(\s(?:href|src|poster|srcset)=")([^"]*)"`)
+
+// FeedContent returns Content for feeds, with relative URLs made absolute
+// unless cfg.Feeds.Links is "keep".
+func (p Post) FeedContent() template.HTML {
+	return feedHTML(p, p.Content)
+}
+
+// FeedSummaryHTML returns SummaryHTML for feeds, like FeedContent.
+func (p Post) FeedSummaryHTML() template.HTML {
+	return feedHTML(p, p.SummaryHTML)
+}
+
+func feedHTML(p Post, content template.HTML) template.HTML {
+	if cfg.Feeds.Links == "keep" {
+		return content
+	}
+	return absolutizeLinks(content, cfg.SiteURL+"/"+p.Path)
+}
+
+// absolutizeLinks rewrites the relative URLs of content's href, src,
+// poster and srcset attributes to absolute ones, resolved against the
+// page URL base as a browser would: root-relative (/x) against its origin,
+// document-relative (x) against its directory, protocol-relative (//x)
+// with its scheme and anchors (#x) against the page itself. Feed readers
+// show content away from the page, where relative URLs break.
+func absolutizeLinks(content template.HTML, base string) template.HTML {
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return content
+	}
+	return template.HTML(urlAttrPattern.ReplaceAllStringFunc(string(content), func(m string) string {
+		sub := urlAttrPattern.FindStringSubmatch(m)
+		value := html.UnescapeString(sub[2])
+		var abs string
+		if strings.HasSuffix(sub[1], `srcset="`) {
+			abs = absoluteSrcset(baseURL, value)
+		} else {
+			abs = absoluteURL(baseURL, value)
+		}
+		if abs == value {
+			return m
+		}
+		return sub[1] + html.EscapeString(abs) + `"`
+	}))
+}
+
+// absoluteURL resolves ref against base. Empty, unparsable and already
+// absolute URLs, like mailto: links, are returned unchanged.
+func absoluteURL(base *url.URL, ref string) string {
+	u, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil || ref == "" || u.Scheme != "" {
+		return ref
+	}
+	return base.ResolveReference(u).String()
+}
+
+// absoluteSrcset resolves the URL of every candidate of a srcset, keeping
+// their width or density descriptors.
+func absoluteSrcset(base *url.URL, srcset string) string {
+	candidates := strings.Split(srcset, ",")
+	for i, c := range candidates {
+		fields := strings.Fields(c)
+		if len(fields) == 0 {
+			continue
+		}
+		fields[0] = absoluteURL(base, fields[0])
+		candidates[i] = strings.Join(fields, " ")
+	}
+	return strings.Join(candidates, ", ")
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"html/template"
+	"testing"
+)
+
+func TestAbsolutizeLinks(t *testing.T) {
+	const base = "https://example.com/notes/post.html"
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"root-relative", `<a href="/about.html">`, `<a href="https://example.com/about.html">`},
+		{"document-relative", `<img src="diagram.png">`, `<img src="https://example.com/notes/diagram.png">`},
+		{"parent", `<a href="../index.html">`, `<a href="https://example.com/index.html">`},
+		{"protocol-relative", `<a href="//cdn.example.net/x.js">`, `<a href="https://cdn.example.net/x.js">`},
+		{"anchor", `<a href="#part">`, `<a href="https://example.com/notes/post.html#part">`},
+		{"query", `<a href="?page=2">`, `<a href="https://example.com/notes/post.html?page=2">`},
+		{"absolute", `<a href="https://other.com/x">`, `<a href="https://other.com/x">`},
+		{"mailto", `<a href="mailto:me@example.com">`, `<a href="mailto:me@example.com">`},
+		{"empty", `<a href="">`, `<a href="">`},
+		{"escaped", `<a href="/search?q=a&amp;b=c">`, `<a href="https://example.com/search?q=a&amp;b=c">`},
+		{"poster", `<video poster="still.jpg">`, `<video poster="https://example.com/notes/still.jpg">`},
+		{"srcset", `<img srcset="small.png 1x, /large.png 2x">`, `<img srcset="https://example.com/notes/small.png 1x, https://example.com/large.png 2x">`},
+		{"srcset widths", `<img srcset="a.png 480w,b.png 800w">`, `<img srcset="https://example.com/notes/a.png 480w, https://example.com/notes/b.png 800w">`},
+		{"text left alone", `<p>href="/x"</p>`, `<p>href="/x"</p>`},
+		{"data attribute left alone", `<a data-href="/x">`, `<a data-href="/x">`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := absolutizeLinks(template.HTML(tt.content), base); string(got) != tt.want {
+				t.Errorf("absolutizeLinks(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
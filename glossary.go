@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/util"
+)
+
+// Term is a definition made with the term shortcode:
+//
+//	{{< term "goroutine" "a lightweight thread managed by the Go runtime" >}}
+type Term struct {
+	Name       string
+	Definition string
+	// ID is the anchor of the definition within its post.
+	ID string
+}
+
+func renderTerm(w util.BufWriter, n *shortcodeNode) {
+	id, _ := n.AttributeString("id")
+	b, _ := id.([]byte)
+	fmt.Fprintf(w, `<dfn id="%s" title="%s">%s</dfn>`,
+		util.EscapeHTML(b), util.EscapeHTML([]byte(n.args[1])), util.EscapeHTML([]byte(n.args[0])))
+}
+
+// collectTerms returns the terms defined in doc, giving each definition
+// an ID unique within the post: "term-" and the term's slug, suffixed with
+// -2, -3 and so on when the slug repeats. The IDs are set on the shortcodes,
+// so it must run before doc is rendered.
+func collectTerms(doc ast.Node) []Term {
+	var terms []Term
+	used := map[string]bool{}
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if sc, ok := n.(*shortcodeNode); ok && entering && sc.name == "term" && len(sc.args) == 2 {
+			t := Term{Name: sc.args[0], Definition: sc.args[1], ID: uniqueID(used, termID(sc.args[0]))}
+			sc.SetAttributeString("id", []byte(t.ID))
+			terms = append(terms, t)
+		}
+		return ast.WalkContinue, nil
+	})
+	return terms
+}
+
+// termID returns the anchor of a definition of the term name, before it
+// is made unique. A name without a slug, like a blank one, uses its hash.
+func termID(name string) string {
+	slug := slugify(name)
+	if slug == "" {
+		slug = hashSlug(name)
+	}
+	return "term-" + slug
+}
+
+// uniqueID returns id, or when used has it, id suffixed with the lowest
+// free number from 2 up, and marks the result used.
+func uniqueID(used map[string]bool, id string) string {
+	unique := id
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d", id, i)
+	}
+	used[unique] = true
+	return unique
+}
+
+// GlossaryEntry is one term on the glossary page with the posts defining it.
+// Its ID, unlike the Term's, is unique on the glossary page.
+type GlossaryEntry struct {
+	Term
+	Refs []TermRef
+}
+
+// TermRef is a post defining a glossary term, with the anchor of its first
+// definition there.
+type TermRef struct {
+	Post Post
+	ID   string
+}
+
+type GlossaryData struct {
+	Entries []GlossaryEntry
+	GAID    string
+}
+
+// buildGlossary merges the terms of all posts, keyed case-insensitively.
+// The first definition of a term wins; differing ones are reported.
+func buildGlossary(posts []Post) []GlossaryEntry {
+	byKey := map[string]*GlossaryEntry{}
+	var keys []string
+	for _, post := range posts {
+		for _, t := range post.Terms {
+			key := strings.ToLower(t.Name)
+			e, ok := byKey[key]
+			if !ok {
+				e = &GlossaryEntry{Term: t}
+				byKey[key] = e
+				keys = append(keys, key)
+			} else if e.Definition != t.Definition {
+				warnf("term %q is defined as %q in %s but as %q in %s", t.Name, e.Definition, e.Refs[0].Post.Slug, t.Definition, post.Slug)
+			}
+			if len(e.Refs) == 0 || e.Refs[len(e.Refs)-1].Post.Path != post.Path {
+				e.Refs = append(e.Refs, TermRef{Post: post, ID: t.ID})
+			}
+		}
+	}
+
+	sort.Strings(keys)
+	entries := make([]GlossaryEntry, 0, len(keys))
+	used := map[string]bool{}
+	for _, key := range keys {
+		e := *byKey[key]
+		e.ID = uniqueID(used, termID(e.Name))
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+func generateGlossary(posts []Post) error {
+	entries := buildGlossary(posts)
+	if len(entries) == 0 {
+		return nil
+	}
+
+	f, err := createOutput("public/glossary.html")
+	if err != nil {
+		return fmt.Errorf("create glossary: %w", err)
+	}
+	defer f.Close()
+
+	if err := glossaryTmpl.Execute(f, GlossaryData{Entries: entries, GAID: gaID}); err != nil {
+		return fmt.Errorf("render glossary: %w", err)
+	}
+	return nil
+}
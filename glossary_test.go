@@ -0,0 +1,79 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+)
+
+var dfnIDPattern = regexp.MustCompile(`<dfn id="([^"]*)"`)
+
+// dfnIDs returns the IDs of the definitions in rendered content.
+func dfnIDs(content string) []string {
+	var ids []string
+	for _, m := range dfnIDPattern.FindAllStringSubmatch(content, -1) {
+		ids = append(ids, m[1])
+	}
+	return ids
+}
+
+func TestTermIDs(t *testing.T) {
+	useDefaultConfig(t)
+	content := "---\ntitle: terms\ndate: 2024-01-01\n---\n\n" +
+		"{{< term \"Go\" \"a language\" >}} and {{< term \"go\" \"a language\" >}}, " +
+		"{{< term \"Go!\" \"excited\" >}}, {{< term \"🚀\" \"a rocket\" >}} and {{< term \"\" \"nothing\" >}}.\n"
+	post, err := parsePost(cfg.ContentTypes[0], "terms.md", []byte(content))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"term-go", "term-go-2", "term-go-3", "term-" + hashSlug("🚀"), "term-" + hashSlug("")}
+	var got []string
+	for _, term := range post.Terms {
+		got = append(got, term.ID)
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("term IDs = %v, want %v", got, want)
+	}
+	if ids := dfnIDs(string(post.Content)); !slices.Equal(ids, want) {
+		t.Errorf("page definition IDs = %v, want %v", ids, want)
+	}
+	var prefixed []string
+	for _, id := range want {
+		prefixed = append(prefixed, "terms-"+id)
+	}
+	if ids := dfnIDs(string(post.AllContent)); !slices.Equal(ids, prefixed) {
+		t.Errorf("all.html definition IDs = %v, want %v", ids, prefixed)
+	}
+}
+
+func TestBuildGlossary(t *testing.T) {
+	a := Post{Title: "a", Slug: "a", Path: "a.html", Terms: []Term{
+		{Name: "C", Definition: "a language", ID: "term-c"},
+		{Name: "c", Definition: "a language", ID: "term-c-2"},
+	}}
+	b := Post{Title: "b", Slug: "b", Path: "b.html", Terms: []Term{
+		{Name: "C!", Definition: "excited", ID: "term-c"},
+		{Name: "c", Definition: "a language", ID: "term-c-2"},
+	}}
+	entries := buildGlossary([]Post{a, b})
+	type ref struct{ path, id string }
+	want := []struct {
+		name, id string
+		refs     []ref
+	}{
+		{"C", "term-c", []ref{{"a.html", "term-c"}, {"b.html", "term-c-2"}}},
+		{"C!", "term-c-2", []ref{{"b.html", "term-c"}}},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("buildGlossary returned %d entries, want %d", len(entries), len(want))
+	}
+	for i, e := range entries {
+		var refs []ref
+		for _, r := range e.Refs {
+			refs = append(refs, ref{r.Post.Path, r.ID})
+		}
+		if e.Name != want[i].name || e.ID != want[i].id || !slices.Equal(refs, want[i].refs) {
+			t.Errorf("entry %d = %s %s %v, want %s %s %v", i, e.Name, e.ID, refs, want[i].name, want[i].id, want[i].refs)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import "sort"
+
+// Translation is one language version of a post, for hreflang links.
+type Translation struct {
+	Lang string
+	URL  string
+}
+
+// linkTranslations sets Translations on every post sharing its
+// translationKey with another post. Each group lists all its versions,
+// the post itself included, ordered by language.
+func linkTranslations(posts []Post) {
+	groups := map[string][]int{}
+	for i, post := range posts {
+		if post.TranslationKey != "" {
+			groups[post.TranslationKey] = append(groups[post.TranslationKey], i)
+		}
+	}
+
+	for key, group := range groups {
+		if len(group) < 2 {
+			continue
+		}
+		var translations []Translation
+		langs := map[string]string{}
+		for _, i := range group {
+			if slug, ok := langs[posts[i].Lang]; ok {
+				warnf("translationKey %q has two %q versions: %s and %s", key, posts[i].Lang, slug, posts[i].Slug)
+				continue
+			}
+			langs[posts[i].Lang] = posts[i].Slug
+			translations = append(translations, Translation{Lang: posts[i].Lang, URL: posts[i].URL()})
+		}
+		sort.Slice(translations, func(a, b int) bool {
+			return translations[a].Lang < translations[b].Lang
+		})
+		for _, i := range group {
+			posts[i].Translations = translations
+		}
+	}
+}
+
+// hasTranslations reports whether any post has translations.
+func hasTranslations(posts []Post) bool {
+	for _, post := range posts {
+		if len(post.Translations) > 0 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// imageEncoder converts a JPEG or PNG into a modern format using an
+// external command, since the standard library has no WebP/AVIF encoder.
+type imageEncoder struct {
+	mimeType string
+	command  string
+	args     func(src, dst string) []string
+}
+
+var imageEncoders = map[string]imageEncoder{
+	"webp": {
+		mimeType: "image/webp",
+		command:  "cwebp",
+		args: func(src, dst string) []string {
+			args := []string{"-quiet", "-q", strconv.Itoa(cfg.ImageQuality.WebP)}
+			if cfg.ImageQuality.KeepMetadata {
+				args = append(args, "-metadata", "exif,xmp")
+			}
+			return append(args, src, "-o", dst)
+		},
+	},
+	"avif": {
+		mimeType: "image/avif",
+		command:  "avifenc",
+		args: func(src, dst string) []string {
+			args := []string{"-q", strconv.Itoa(cfg.ImageQuality.AVIF)}
+			if !cfg.ImageQuality.KeepMetadata {
+				args = append(args, "--ignore-exif", "--ignore-xmp")
+			}
+			return append(args, src, dst)
+		},
+	},
+}
+
+var (
+	// imageFormats lists the configured formats whose encoder is installed,
+	// in the order their <source> elements are emitted.
+	imageFormats []string
+
+	// imageJobs maps a static source image to the variants it needs.
+	imageJobs   = map[string][]string{}
+	imageJobsMu sync.Mutex
+)
+
+type imageSource struct {
+	URL  string
+	Type string
+}
+
+// availableImageFormats filters formats down to those whose encoder can be
+// found on PATH, warning about the rest.
+func availableImageFormats(formats []string) []string {
+	var available []string
+	for _, format := range formats {
+		enc := imageEncoders[format]
+		if _, err := exec.LookPath(enc.command); err != nil {
+			warnf("%s encoder %q not found, skipping %s variants", format, enc.command, format)
+			continue
+		}
+		available = append(available, format)
+	}
+	return available
+}
+
+// pictureSources returns the modern-format variants for an image URL and
+// queues them for generation. Remote, missing, SVG and GIF images get none.
+func pictureSources(url string) []imageSource {
+	if len(imageFormats) == 0 || strings.HasPrefix(url, "//") || (!strings.HasPrefix(url, "/") && !isRelativePath(url)) {
+		return nil
+	}
+
+	ext := strings.ToLower(path.Ext(url))
+	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" {
+		return nil
+	}
+
+	src := filepath.Join("static", filepath.FromSlash(strings.TrimPrefix(url, "/")))
+	if _, err := os.Stat(src); err != nil {
+		return nil
+	}
+
+	imageJobsMu.Lock()
+	defer imageJobsMu.Unlock()
+	var sources []imageSource
+	for _, format := range imageFormats {
+		variant := strings.TrimSuffix(url, path.Ext(url)) + "." + format
+		sources = append(sources, imageSource{URL: variant, Type: imageEncoders[format].mimeType})
+		if !slices.Contains(imageJobs[src], format) {
+			imageJobs[src] = append(imageJobs[src], format)
+		}
+	}
+	return sources
+}
+
+// generateImageVariants runs the encoders for every image queued by
+// pictureSources, writing the variants next to their copies in dstDir.
+// Each variant is a job of its own, in source order.
+func generateImageVariants(ctx context.Context, srcDir, dstDir string) error {
+	type variant struct{ src, format string }
+	var variants []variant
+	for src, formats := range imageJobs {
+		for _, format := range formats {
+			variants = append(variants, variant{src, format})
+		}
+	}
+	sort.Slice(variants, func(i, j int) bool {
+		if variants[i].src != variants[j].src {
+			return variants[i].src < variants[j].src
+		}
+		return variants[i].format < variants[j].format
+	})
+
+	return parallel(ctx, len(variants), func(_ context.Context, i int) error {
+		src, format := variants[i].src, variants[i].format
+		rel, err := filepath.Rel(srcDir, src)
+		if err != nil {
+			return err
+		}
+		enc := imageEncoders[format]
+		dst := filepath.Join(dstDir, strings.TrimSuffix(rel, filepath.Ext(rel))+"."+format)
+		// The encoder writes dst itself, past the output helpers.
+		if protectedOutput(dst) {
+			return nil
+		}
+		if err := mkdirOutput(filepath.Dir(dst)); err != nil {
+			return fmt.Errorf("create image dir for %s: %w", dst, err)
+		}
+		out, err := exec.Command(enc.command, enc.args(src, dst)...).CombinedOutput()
+		if err != nil {
+			return fmt.Errorf("encode %s as %s: %w: %s", src, format, err, out)
+		}
+		if err := chmodOutput(dst); err != nil {
+			return fmt.Errorf("chmod %s: %w", dst, err)
+		}
+		return nil
+	})
+}
+
+var kindPicture = ast.NewNodeKind("Picture")
+
+// pictureNode wraps an image in a <picture> element with one <source> per
+// modern-format variant. The image itself renders as the fallback.
+type pictureNode struct {
+	ast.BaseInline
+	sources []imageSource
+}
+
+func (n *pictureNode) Kind() ast.NodeKind { return kindPicture }
+
+func (n *pictureNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// pictureTransformer wraps every content image that has variants in a
+// pictureNode.
+type pictureTransformer struct{}
+
+func (pictureTransformer) Transform(doc *ast.Document, _ text.Reader, _ parser.Context) {
+	if len(imageFormats) == 0 {
+		return
+	}
+
+	var images []*ast.Image
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if img, ok := n.(*ast.Image); ok && entering {
+			images = append(images, img)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	for _, img := range images {
+		sources := pictureSources(string(img.Destination))
+		if len(sources) == 0 {
+			continue
+		}
+		picture := &pictureNode{sources: sources}
+		img.Parent().ReplaceChild(img.Parent(), img, picture)
+		picture.AppendChild(picture, img)
+	}
+}
+
+type pictureRenderer struct{}
+
+func (pictureRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindPicture, func(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			_, _ = w.WriteString("</picture>")
+			return ast.WalkContinue, nil
+		}
+		_, _ = w.WriteString("<picture>")
+		for _, s := range node.(*pictureNode).sources {
+			fmt.Fprintf(w, `<source srcset="%s" type="%s">`, util.EscapeHTML(util.URLEscape([]byte(s.URL), true)), s.Type)
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// imgSrcPattern matches the src of every <img> in rendered content, from
+// markdown images and raw HTML alike.
+var imgSrcPattern = regexp.MustCompile(`<img\s[^>]*?\bsrc="([^"]*)"`)
+
+// firstImage returns the first local image in doc, the content of the page
+// at site-relative page, relative to the site root, or "" if there is none.
+func firstImage(doc ast.Node, page string) string {
+	base := path.Dir("/" + page)
+	var src string
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		img, ok := n.(*ast.Image)
+		if !ok || !entering {
+			return ast.WalkContinue, nil
+		}
+		dest := string(img.Destination)
+		if strings.HasPrefix(dest, "/") && !strings.HasPrefix(dest, "//") {
+			src = dest
+		} else if isRelativePath(dest) {
+			src = path.Join(base, dest)
+		}
+		if src == "" {
+			return ast.WalkContinue, nil
+		}
+		return ast.WalkStop, nil
+	})
+	return strings.TrimPrefix(src, "/")
+}
+
+// checkImages warns about covers and content images that point to a local
+// file missing from static/. Remote and data URIs are not checked.
+func checkImages(posts []Post) {
+	for _, post := range posts {
+		source := filepath.Join(post.Type.Dir, post.Filename)
+		if post.Cover != "" {
+			checkImage(source, "cover", "/", post.Cover)
+		}
+		pageDir := path.Dir("/" + post.Path)
+		for _, m := range imgSrcPattern.FindAllStringSubmatch(string(post.Content), -1) {
+			checkImage(source, "image", pageDir, html.UnescapeString(m[1]))
+		}
+	}
+}
+
+// checkCovers warns about publishable posts with neither a cover nor a
+// content image when cfg.RequireCover is set, except for content types with
+// CoverOptional. A defaultCover covers every post, so then it only checks
+// that one.
+func checkCovers(posts []Post) {
+	if !cfg.RequireCover {
+		return
+	}
+	if cfg.DefaultCover != "" {
+		checkImage(configPath, "defaultCover", "/", cfg.DefaultCover)
+		return
+	}
+	for _, post := range publishablePosts(posts) {
+		if post.Cover == "" && post.FirstImage == "" && !post.Type.CoverOptional {
+			warnf("%s has no cover or image; set cover in its front matter or defaultCover in %s", filepath.Join(post.Type.Dir, post.Filename), configPath)
+		}
+	}
+}
+
+// checkImage warns unless the image at src, resolved against the page
+// directory base, exists under static/.
+func checkImage(source, what, base, src string) {
+	if src == "" || strings.HasPrefix(src, "//") || strings.HasPrefix(src, "#") {
+		return
+	}
+	if i := strings.IndexAny(src, ":/?#"); i >= 0 && src[i] == ':' {
+		return
+	}
+	local, _, _ := strings.Cut(src, "?")
+	local, _, _ = strings.Cut(local, "#")
+	if unescaped, err := url.PathUnescape(local); err == nil {
+		local = unescaped
+	}
+	if !strings.HasPrefix(local, "/") {
+		local = path.Join(base, local)
+	}
+	file := filepath.Join("static", filepath.FromSlash(path.Clean(local)))
+	if _, err := os.Stat(file); err != nil {
+		warnf("%s %q in %s not found: %s does not exist", what, src, source, file)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImageVariantsKeepProtected(t *testing.T) {
+	if _, err := os.Stat("/bin/sh"); err != nil {
+		t.Skip("needs /bin/sh to stand in for an encoder")
+	}
+	dir := t.TempDir()
+	for _, sub := range []string{"static", "public"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"static/kept.png", "static/new.png", "public/kept.webp"} {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte("old\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(dir)
+
+	saved, savedEncoders, savedJobs, savedState, savedOutputs := cfg, imageEncoders, imageJobs, prevState, outputs
+	t.Cleanup(func() {
+		cfg, imageEncoders, imageJobs, prevState, outputs = saved, savedEncoders, savedJobs, savedState, savedOutputs
+	})
+	cfg.ProtectedPaths = []string{"*.webp"}
+	prevState = nil
+	outputs = map[string]bool{}
+	imageEncoders = map[string]imageEncoder{"webp": {
+		command: "/bin/sh",
+		args:    func(src, dst string) []string { return []string{"-c", `echo new > "$0"`, dst} },
+	}}
+	imageJobs = map[string][]string{"static/kept.png": {"webp"}, "static/new.png": {"webp"}}
+
+	if err := generateImageVariants(context.Background(), "static", "public"); err != nil {
+		t.Fatal(err)
+	}
+	for file, want := range map[string]string{"public/kept.webp": "old\n", "public/new.webp": "new\n"} {
+		if got, err := os.ReadFile(file); err != nil || string(got) != want {
+			t.Errorf("%s = %q, %v, want %q", file, got, err, want)
+		}
+	}
+}
+
+func TestCheckCovers(t *testing.T) {
+	tests := []struct {
+		name         string
+		cover        string
+		firstImage   string
+		defaultCover string
+		optional     bool
+		warn         bool
+	}{
+		{"explicit cover", "cover.png", "", "", false, false},
+		{"first image", "", "diagram.png", "", false, false},
+		{"default cover", "", "", "default.png", false, false},
+		{"missing default cover", "", "", "missing.png", false, true},
+		{"optional", "", "", "", true, false},
+		{"none", "", "", "", false, true},
+	}
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "static"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "static", "default.png"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Chdir(dir)
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	cfg.RequireCover = true
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.DefaultCover = tt.defaultCover
+			post := Post{
+				Filename:   "post.md",
+				Cover:      tt.cover,
+				FirstImage: tt.firstImage,
+				Type:       contentType{Dir: "posts", CoverOptional: tt.optional},
+			}
+			before := warnings
+			checkCovers([]Post{post})
+			if got := warnings > before; got != tt.warn {
+				t.Errorf("warned: %v, want %v", got, tt.warn)
+			}
+		})
+	}
+}
+
+func TestFirstImage(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"relative", "![a](img/a.png) ![b](/b.png)", "notes/img/a.png"},
+		{"absolute", "![b](/b.png)", "b.png"},
+		{"remote skipped", "![r](https://example.com/r.png) ![b](/b.png)", "b.png"},
+		{"protocol-relative skipped", "![r](//example.com/r.png)", ""},
+		{"none", "No images.", ""},
+	}
+	useDefaultConfig(t)
+	ct := cfg.ContentTypes[0]
+	ct.URLPrefix = "notes/"
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post, err := parsePost(ct, "x.md", []byte("---\ntitle: x\ndate: 2024-01-01\n---\n\n"+tt.body+"\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if post.FirstImage != tt.want {
+				t.Errorf("FirstImage = %q, want %q", post.FirstImage, tt.want)
+			}
+			if tt.want != "" && post.ShareImage() != tt.want {
+				t.Errorf("ShareImage = %q, want %q", post.ShareImage(), tt.want)
+			}
+		})
+	}
+}
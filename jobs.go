@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+type jobsKey struct{}
+
+// withJobs returns a context carrying the build's limit on concurrent
+// work, set with -j: a semaphore with n slots, shared by every stage.
+func withJobs(ctx context.Context, n int) context.Context {
+	return context.WithValue(ctx, jobsKey{}, make(chan struct{}, n))
+}
+
+// parallel calls fn for every index below n, running as many at once as
+// the semaphore in ctx has free slots. With a single slot, or none in
+// ctx, the calls run in order on the calling goroutine, for deterministic
+// builds. The first error, by index, is returned; calls not started by
+// then are skipped. Stages must not nest calls to parallel, or they can
+// wait on slots their callers hold.
+func parallel(ctx context.Context, n int, fn func(ctx context.Context, i int) error) error {
+	sem, _ := ctx.Value(jobsKey{}).(chan struct{})
+	if cap(sem) <= 1 {
+		for i := range n {
+			if err := fn(ctx, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		if !acquire(ctx, sem) {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := fn(ctx, i); err != nil {
+				errs[i] = err
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// acquire takes a slot of sem, or reports false once ctx is done.
+func acquire(ctx context.Context, sem chan struct{}) bool {
+	select {
+	case sem <- struct{}{}:
+		if ctx.Err() != nil {
+			<-sem
+			return false
+		}
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
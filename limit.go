@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"go.abhg.dev/goldmark/frontmatter"
+)
+
+// metaParser reads only the front matter of a post, so --limit can pick
+// posts without rendering all of them.
+var metaParser = goldmark.New(goldmark.WithExtensions(&frontmatter.Extender{})).Parser()
+
+// postLimit is the set of posts a --limit build generates.
+type postLimit struct {
+	// keep holds the source files to build, as content dir/name.md.
+	keep map[string]bool
+	// omitted holds the slugs of the posts left out.
+	omitted map[string]bool
+	total   int
+}
+
+// newPostLimit picks the limit newest non-draft posts across all content
+// types, by the date in their front matter.
+func newPostLimit(limit int) (*postLimit, error) {
+	type candidate struct {
+		file, slug string
+		date       time.Time
+	}
+	var candidates []candidate
+	for _, ct := range cfg.ContentTypes {
+		entries, err := os.ReadDir(ct.Dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
+				continue
+			}
+			file := filepath.Join(ct.Dir, entry.Name())
+			meta, err := readPostMeta(file)
+			if err != nil {
+				return nil, err
+			}
+			if meta.Draft {
+				continue
+			}
+			slug, fileDate := postSlug(entry.Name())
+			if meta.Date == "" {
+				meta.Date = fileDate
+			}
+			date, err := parseDate(meta.Date)
+			if err != nil {
+				return nil, fmt.Errorf("invalid date %q in %s: %w", meta.Date, entry.Name(), err)
+			}
+			candidates = append(candidates, candidate{file, slug, date})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].date.After(candidates[j].date)
+	})
+	l := &postLimit{keep: map[string]bool{}, omitted: map[string]bool{}, total: len(candidates)}
+	for i, c := range candidates {
+		if i < limit {
+			l.keep[c.file] = true
+		} else {
+			l.omitted[c.slug] = true
+		}
+	}
+	return l, nil
+}
+
+// readPostMeta decodes the front matter of the post at file.
+func readPostMeta(file string) (postMeta, error) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return postMeta{}, fmt.Errorf("read post %s: %w", file, err)
+	}
+	name := filepath.Base(file)
+	// Invalid UTF-8 is reported by the full parse, if the post is kept.
+	content, err = transcode(content)
+	if err != nil {
+		return postMeta{}, fmt.Errorf("parse post %s: %w", name, err)
+	}
+	content = bytes.ToValidUTF8(content, []byte("\uFFFD"))
+	ctx := parser.NewContext()
+	metaParser.Parse(text.NewReader(content), parser.WithContext(ctx))
+	d := frontmatter.Get(ctx)
+	if d == nil {
+		return postMeta{}, fmt.Errorf("missing front matter in %s", name)
+	}
+	var meta postMeta
+	if err := d.Decode(&meta); err != nil {
+		return postMeta{}, fmt.Errorf("invalid front matter in %s: %w", name, err)
+	}
+	return meta, nil
+}
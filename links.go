@@ -0,0 +1,100 @@
+package main
+
+import (
+	"html"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// hrefPattern matches the href of every link in rendered content.
+var hrefPattern = regexp.MustCompile(`<a\s[^>]*?\bhref="([^"]*)"`)
+
+// pageFile resolves a site-relative page path, without a leading slash, to
+// the file that serves it, the way GitHub Pages does: "dir/" and "dir"
+// serve dir/index.html, and "page" serves page.html, but "page/" does not.
+// So /some-page/, /some-page and /some-page/index.html all resolve to the
+// same file. exists reports whether a site-relative file exists.
+func pageFile(p string, exists func(string) bool) (string, bool) {
+	dir := p == "" || strings.HasSuffix(p, "/")
+	p = strings.TrimPrefix(path.Clean("/"+p), "/")
+	candidates := []string{p, p + ".html", path.Join(p, "index.html")}
+	if dir {
+		candidates = []string{path.Join(p, "index.html")}
+	}
+	for _, c := range candidates {
+		if exists(c) {
+			return c, true
+		}
+	}
+	return "", false
+}
+
+// checkLinks warns about links in posts to pages of the site that were not
+// built, when cfg.CheckLinks is set. It runs once public is complete, so
+// links to any generated page or static file resolve. Only this build's
+// outputs count, so stale files left in public do not hide broken links;
+// protected paths count wherever they came from.
+func checkLinks(posts []Post) {
+	if !cfg.CheckLinks {
+		return
+	}
+	exists := func(rel string) bool {
+		file := filepath.Join("public", filepath.FromSlash(rel))
+		outputsMu.Lock()
+		written := outputs[filepath.ToSlash(file)]
+		outputsMu.Unlock()
+		if written {
+			return true
+		}
+		if !isProtectedPath(rel) {
+			return false
+		}
+		info, err := os.Stat(file)
+		return err == nil && !info.IsDir()
+	}
+	for _, post := range posts {
+		source := filepath.Join(post.Type.Dir, post.Filename)
+		pageDir := path.Dir("/" + post.Path)
+		for _, m := range hrefPattern.FindAllStringSubmatch(string(post.Content), -1) {
+			href := html.UnescapeString(m[1])
+			target, ok := linkTarget(href, pageDir)
+			if !ok {
+				continue
+			}
+			if _, ok := pageFile(target, exists); !ok {
+				warnf("%s links to %s, which does not exist", source, href)
+			}
+		}
+	}
+}
+
+// linkTarget returns the site-relative path an internal link points to,
+// resolved against the linking page's directory. Links to other sites,
+// other schemes and fragments of the same page are not internal.
+func linkTarget(href, pageDir string) (string, bool) {
+	u, err := url.Parse(href)
+	if err != nil || u.Opaque != "" {
+		return "", false
+	}
+	if u.Scheme != "" || u.Host != "" {
+		if u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" {
+			return "", false
+		}
+		return internalPath(href)
+	}
+	if u.Path == "" {
+		return "", false
+	}
+	p := u.Path
+	if !strings.HasPrefix(p, "/") {
+		p = path.Join(pageDir, p)
+		if strings.HasSuffix(u.Path, "/") {
+			p += "/"
+		}
+	}
+	return strings.TrimPrefix(p, "/"), true
+}
@@ -0,0 +1,77 @@
+package main
+
+import "testing"
+
+func TestPageFile(t *testing.T) {
+	files := map[string]bool{
+		"index.html":       true,
+		"page.html":        true,
+		"dir/index.html":   true,
+		"style.css":        true,
+		"both.html":        true,
+		"both/index.html":  true,
+		"nested/page.html": true,
+	}
+	exists := func(rel string) bool { return files[rel] }
+	tests := []struct {
+		path   string
+		want   string
+		wantOK bool
+	}{
+		{"", "index.html", true},
+		{"index.html", "index.html", true},
+		{"page", "page.html", true},
+		{"page.html", "page.html", true},
+		{"dir", "dir/index.html", true},
+		{"dir/", "dir/index.html", true},
+		{"dir/index.html", "dir/index.html", true},
+		{"style.css", "style.css", true},
+		{"both", "both.html", true},
+		{"nested/../page", "page.html", true},
+		{"../page", "page.html", true},
+		{"missing", "", false},
+		{"page/", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := pageFile(tt.path, exists)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("pageFile(%q) = %q, %v, want %q, %v", tt.path, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
+
+func TestLinkTarget(t *testing.T) {
+	tests := []struct {
+		href    string
+		pageDir string
+		want    string
+		wantOK  bool
+	}{
+		{"/page.html", "/", "page.html", true},
+		{"/dir/", "/", "dir/", true},
+		{"page.html", "/", "page.html", true},
+		{"page.html", "/notes", "notes/page.html", true},
+		{"../page.html", "/notes", "page.html", true},
+		{"sub/", "/notes", "notes/sub/", true},
+		{"/page.html#part", "/", "page.html", true},
+		{"/page.html?q=1", "/", "page.html", true},
+		{"https://example.com/page.html", "/", "page.html", true},
+		{"//example.com/page.html", "/", "page.html", true},
+		{"https://other.com/page.html", "/", "", false},
+		{"#part", "/", "", false},
+		{"?q=1", "/", "", false},
+		{"mailto:me@example.com", "/", "", false},
+		{"ftp://example.com/file", "/", "", false},
+		{"%zz", "/", "", false},
+	}
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	cfg.SiteURL = "https://example.com"
+	cfg.AlternateSiteURL = ""
+	for _, tt := range tests {
+		got, ok := linkTarget(tt.href, tt.pageDir)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("linkTarget(%q, %q) = %q, %v, want %q, %v", tt.href, tt.pageDir, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -2,43 +2,108 @@ package main
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha1"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"html/template"
+	"net/url"
 	"os"
+	"os/exec"
+	"path"
 	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	texttemplate "text/template"
 	"time"
+	"unicode/utf8"
 
 	"github.com/yuin/goldmark"
 	highlighting "github.com/yuin/goldmark-highlighting/v2"
+	"github.com/yuin/goldmark/extension"
 	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
 	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
 	"go.abhg.dev/goldmark/frontmatter"
 )
 
 const (
 	dateLayout        = "2006-01-02"
 	dateDisplayLayout = "Jan 02, 2006"
-	siteURL           = "https://otrv.dev"
+	defaultSiteURL    = "https://otrv.dev"
+	defaultAuthor     = "Özgür Tanrıverdi"
 	gaID              = "G-DZ4KVNJVCR"
 )
 
 var (
+	// buildTime is the reference time for age-dependent output.
+	buildTime = time.Now()
+
 	md = goldmark.New(
 		goldmark.WithExtensions(
 			highlighting.NewHighlighting(
 				highlighting.WithStyle("vim"),
 			),
 			&frontmatter.Extender{},
+			extension.NewFootnote(
+				extension.WithFootnoteIDPrefixFunction(footnotePrefix),
+			),
+		),
+		goldmark.WithParserOptions(
+			parser.WithAutoHeadingID(),
+			parser.WithBlockParsers(
+				util.Prioritized(pairedShortcodeParser{}, 50),
+			),
+			parser.WithInlineParsers(
+				util.Prioritized(shortcodeParser{}, 500),
+			),
+			parser.WithASTTransformers(
+				util.Prioritized(pathRewriter{}, 100),
+				util.Prioritized(pictureTransformer{}, 200),
+				util.Prioritized(externalLinks{}, 300),
+				util.Prioritized(diagramTransformer{}, 400),
+				util.Prioritized(codeCopyTransformer{}, 450),
+				util.Prioritized(leadParagraph{}, 500),
+				// After the footnote transformer at 999, which numbers
+				// footnotes and collects them at the bottom.
+				util.Prioritized(sidenotes{}, 1000),
+			),
+		),
+		goldmark.WithRendererOptions(
+			renderer.WithNodeRenderers(
+				util.Prioritized(pictureRenderer{}, 500),
+				util.Prioritized(shortcodeRenderer{}, 500),
+				util.Prioritized(diagramRenderer{}, 500),
+				util.Prioritized(tocRenderer{}, 500),
+				util.Prioritized(codeCopyRenderer{}, 500),
+				util.Prioritized(sidenoteRenderer{}, 500),
+			),
 		),
 	)
 
-	postTmpl  = template.Must(template.ParseFiles("templates/post.gohtml"))
-	indexTmpl = template.Must(template.ParseFiles("templates/index.gohtml"))
-	feedTmpl  = texttemplate.Must(texttemplate.New("feed.xml").Funcs(texttemplate.FuncMap{
+	// siteFuncs are available to every template.
+	siteFuncs = map[string]any{
+		"siteURL": func() string { return cfg.SiteURL },
+		// feedPath is the site-relative URL of the canonical feed.
+		"feedPath": func() string { return cfg.Feeds.Path },
+		// printStylesheet is the URL of the print CSS of printable posts.
+		"printStylesheet": func() string { return cfg.PrintStylesheet },
+		// bundle is the URL of a bundle from the bundles config, and asset
+		// that of a static file, bundled or not.
+		"bundle": bundleURL,
+		"asset":  assetURL,
+	}
+
+	// xmlFuncs are available to the XML templates.
+	xmlFuncs = texttemplate.FuncMap{
 		"escape": func(s string) string {
 			var buf bytes.Buffer
 			template.HTMLEscape(&buf, []byte(s))
@@ -48,18 +113,125 @@ var (
 			str := fmt.Sprintf("%v", s)
 			return strings.ReplaceAll(str, "]]>", "]]]]><![CDATA[>")
 		},
-	}).ParseFiles("templates/feed.xml"))
-	sitemapTmpl = texttemplate.Must(texttemplate.ParseFiles("templates/sitemap.xml"))
+	}
+
+	htmlTemplates, textTemplates = mustLoadTemplates("templates")
+
+	indexTmpl    = mustLookupHTML("index.gohtml")
+	allTmpl      = mustLookupHTML("all.gohtml")
+	featuredTmpl = mustLookupHTML("featured.gohtml")
+	glossaryTmpl = mustLookupHTML("glossary.gohtml")
+	authorTmpl   = mustLookupHTML("author.gohtml")
+	redirectTmpl = mustLookupHTML("redirect.gohtml")
+
+	taxonomyIndexTmpl = mustLookupHTML("taxonomy-index.gohtml")
+
+	feedTmpl    = mustLookupText("feed.xml")
+	updatesTmpl = mustLookupText("updates.xml")
+	opmlTmpl    = mustLookupText("feeds.opml")
+	sitemapTmpl = mustLookupText("sitemap.xml")
 )
 
 type Post struct {
-	Title       string
-	Date        time.Time
+	Title string
+	Date  time.Time
+	// Updated is the date of the last meaningful revision, zero if none.
+	Updated time.Time
+	// UpdateNote says what the revision at Updated changed, for the
+	// updates feed.
+	UpdateNote string
+	// ShowTime is set for posts that display the time of day of Date.
+	ShowTime    bool
 	Description string
 	Cover       string
-	Slug        string
-	Content     template.HTML
-	JSONLD      template.JS
+	// CoverSources are the modern-format variants of Cover, if any.
+	CoverSources []imageSource
+	// FirstImage is the first local image in the content, relative to the
+	// site root; it stands in for a missing cover in social previews.
+	FirstImage string
+	// SocialImage is the generated social card of posts with neither a cover
+	// nor a FirstImage, relative to the site root. See generateSocialImages.
+	SocialImage string
+	// QRCode is the QR code image of the post's canonical URL, relative to
+	// the site root, when qrCodes is enabled. See generateQRCodes.
+	QRCode string
+	Slug   string
+	// Filename is the post's markdown file name in its type's Dir.
+	Filename string
+	// RelatedSlugs are the slugs from the related front matter, which
+	// linkRelated resolves into Related.
+	RelatedSlugs []string
+	// Related are the posts listed under this one. See linkRelated.
+	Related []Post
+	// Canonical is the absolute canonical URL from front matter, empty
+	// when the post is canonical itself. See CanonicalURL.
+	Canonical string
+	// EditLink is false for posts opting out of the edit link.
+	EditLink bool
+	// Path is the page's URL relative to the site root, e.g.
+	// "notes/some-note.html".
+	Path string
+	// Type is the content type the post was read as.
+	Type contentType
+	// Layout is the template name from front matter, empty for the default.
+	Layout  string
+	Authors []Author
+	Words   int
+	// Sections are the top-level sections with their word counts.
+	Sections []Section
+	// TOC shows the table of contents in the template's spot, unless
+	// InlineTOC says a marker in the post already placed it.
+	TOC       bool
+	InlineTOC bool
+	// Priority overrides the age-based sitemap priority when set.
+	Priority *float64
+	Featured bool
+	// FeaturedWeight orders featured posts; higher comes first.
+	FeaturedWeight int
+	// Pinned posts come first in listings, then posts by descending
+	// Weight. See listingOrder.
+	Pinned bool
+	Weight int
+	// InFeed and InIndex are false for posts whose front matter keeps
+	// them out of the feeds or the listing pages.
+	InFeed  bool
+	InIndex bool
+	// Terms are the definitions made with the term shortcode.
+	Terms []Term
+	Tags  []string
+	// Taxonomies maps each configured taxonomy to the post's terms in it.
+	Taxonomies map[string][]string
+	// SummaryHTML is the excerpt for the feed and index, see
+	// renderSummary, and SummaryText the same without markup for meta
+	// tags.
+	SummaryHTML template.HTML
+	SummaryText string
+	Lang        string
+	// TranslationKey groups translations of the same content, and
+	// Translations lists the group's versions when it has more than one.
+	TranslationKey string
+	Translations   []Translation
+	Draft          bool
+	Unlisted       bool
+	Printable      bool
+	// HasMath and HasMermaid tell templates which client-side assets the
+	// post needs.
+	HasMath    bool
+	HasMermaid bool
+	// HasCopyButtons is set when code blocks got a copy button, which
+	// needs copy.js.
+	HasCopyButtons bool
+	// Expires is the expiryDate after which the post is no longer listed,
+	// zero if none.
+	Expires time.Time
+	// FirstPublished is when a build first listed the post, from the
+	// build state; see setFirstPublished. Zero for posts not listed.
+	FirstPublished time.Time
+	Content        template.HTML
+	// AllContent is Content with heading IDs and in-page anchors prefixed
+	// by the slug, so every post can share all.html without collisions.
+	AllContent template.HTML
+	JSONLD     template.JS
 }
 
 type jsonLD struct {
@@ -68,6 +240,7 @@ type jsonLD struct {
 	Headline         string       `json:"headline"`
 	Description      string       `json:"description,omitempty"`
 	DatePublished    string       `json:"datePublished"`
+	DateModified     string       `json:"dateModified,omitempty"`
 	Author           jsonLDPerson `json:"author"`
 	Publisher        jsonLDPerson `json:"publisher"`
 	MainEntityOfPage jsonLDPage   `json:"mainEntityOfPage"`
@@ -85,10 +258,36 @@ type jsonLDPage struct {
 	ID   string `json:"@id"`
 }
 
+type jsonLDWebSite struct {
+	Context         string             `json:"@context"`
+	Type            string             `json:"@type"`
+	Name            string             `json:"name"`
+	URL             string             `json:"url"`
+	PotentialAction jsonLDSearchAction `json:"potentialAction"`
+}
+
+type jsonLDSearchAction struct {
+	Type       string `json:"@type"`
+	Target     string `json:"target"`
+	QueryInput string `json:"query-input"`
+}
+
 func (p Post) DateString() string {
+	if p.ShowTime {
+		return p.Date.Format(dateDisplayLayout + " " + cfg.TimeLayout)
+	}
 	return p.Date.Format(dateDisplayLayout)
 }
 
+// DateAttr returns the date for a <time datetime> attribute, including the
+// time of day when the post shows it.
+func (p Post) DateAttr() string {
+	if p.ShowTime {
+		return p.DateRFC3339()
+	}
+	return p.DateISO()
+}
+
 func (p Post) DateISO() string {
 	return p.Date.Format(dateLayout)
 }
@@ -97,10 +296,287 @@ func (p Post) DateRFC3339() string {
 	return p.Date.Format(time.RFC3339)
 }
 
+// LastModified returns Updated, or Date for posts never updated.
+func (p Post) LastModified() time.Time {
+	if p.Updated.IsZero() {
+		return p.Date
+	}
+	return p.Updated
+}
+
+func (p Post) UpdatedRFC3339() string {
+	return p.LastModified().Format(time.RFC3339)
+}
+
+// RelativeDate describes the publish date relative to the build time,
+// e.g. "3 days ago".
+func (p Post) RelativeDate() string {
+	return p.RelativeDateAt(buildTime)
+}
+
+func (p Post) RelativeDateAt(now time.Time) string {
+	return relativeTime(p.Date, now)
+}
+
+// RelativeUpdated describes the update date relative to the build time. It
+// is empty for posts never updated.
+func (p Post) RelativeUpdated() string {
+	return p.RelativeUpdatedAt(buildTime)
+}
+
+func (p Post) RelativeUpdatedAt(now time.Time) string {
+	if p.Updated.IsZero() {
+		return ""
+	}
+	return relativeTime(p.Updated, now)
+}
+
+// URL returns the absolute canonical URL of the post.
+func (p Post) URL() string {
+	return cfg.SiteURL + "/" + p.Path
+}
+
+// CanonicalURL returns the URL search engines should index the post
+// under: its canonical override, or else its own URL.
+func (p Post) CanonicalURL() string {
+	if p.Canonical != "" {
+		return p.Canonical
+	}
+	return p.URL()
+}
+
+// EditURL returns the link to edit the post's source from
+// cfg.EditURLTemplate, or "" when there is none or the post opted out.
+// Each segment of the substituted path is escaped.
+func (p Post) EditURL() string {
+	if cfg.EditURLTemplate == "" || !p.EditLink {
+		return ""
+	}
+	return strings.NewReplacer(
+		"{filename}", url.PathEscape(p.Filename),
+		"{path}", escapePath(path.Join(filepath.ToSlash(p.Type.Dir), p.Filename)),
+	).Replace(cfg.EditURLTemplate)
+}
+
+// escapePath escapes each segment of the slash-separated p for a URL path.
+func escapePath(p string) string {
+	segments := strings.Split(p, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// FeedAuthors returns the authors of the post's feed entry: its own, or
+// the site's author when it names none.
+func (p Post) FeedAuthors() []Author {
+	if len(p.Authors) > 0 {
+		return p.Authors
+	}
+	return []Author{cfg.Author}
+}
+
+// SitemapPriority returns the post's sitemap priority, taken from its front
+// matter or else from the first age tier it falls within.
+func (p Post) SitemapPriority() string {
+	if p.Priority != nil {
+		return formatPriority(*p.Priority)
+	}
+
+	age := buildTime.Sub(p.Date)
+	for _, tier := range cfg.Sitemap.PriorityTiers {
+		if tier.MaxAgeDays == 0 || age <= time.Duration(tier.MaxAgeDays)*24*time.Hour {
+			return formatPriority(tier.Priority)
+		}
+	}
+	// No catch-all tier: fall back to the sitemap protocol default.
+	return formatPriority(0.5)
+}
+
+func formatPriority(p float64) string {
+	return strconv.FormatFloat(p, 'f', 1, 64)
+}
+
+// FeedID returns the entry ID used in feeds according to cfg.FeedIDStrategy.
+// URL IDs are readable but change with siteURL. Slug IDs only change when
+// the post is renamed; they include the type's URLPrefix, so posts of
+// different types sharing a slug get distinct IDs. Hash IDs change whenever the content does, which
+// makes readers show every edit as a new entry.
+func (p Post) FeedID() string {
+	switch cfg.FeedIDStrategy {
+	case "slug":
+		return nameUUID("slug:" + p.Type.URLPrefix + p.Slug)
+	case "hash":
+		return nameUUID("content:" + string(p.Content))
+	default:
+		return p.URL()
+	}
+}
+
+// UpdateFeedID identifies the post's latest revision in the updates
+// feed, so every new Updated date is a new entry.
+func (p Post) UpdateFeedID() string {
+	return nameUUID("update:" + p.FeedID() + ":" + p.UpdatedRFC3339())
+}
+
+// nameUUID returns a name-based (version 5) UUID URN for name.
+func nameUUID(name string) string {
+	sum := sha1.Sum([]byte(name))
+	sum[6] = sum[6]&0x0f | 0x50
+	sum[8] = sum[8]&0x3f | 0x80
+	return fmt.Sprintf("urn:uuid:%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])
+}
+
+// IsStale reports whether the post is older than cfg.StaleAfterYears at
+// build time.
+func (p Post) IsStale() bool {
+	return p.IsStaleAt(buildTime)
+}
+
+// IsStaleAt reports whether the post is older than cfg.StaleAfterYears at
+// now.
+func (p Post) IsStaleAt(now time.Time) bool {
+	if cfg.StaleAfterYears == 0 {
+		return false
+	}
+	return p.Date.AddDate(cfg.StaleAfterYears, 0, 0).Before(now)
+}
+
+// Publishable reports whether the post is listed on the index, feeds,
+// sitemap and every other page linking to posts, at build time.
+func (p Post) Publishable() bool {
+	return p.PublishableAt(buildTime)
+}
+
+// PublishableAt reports whether the post is listed at now: it is not
+// unlisted, its date has come and it has not expired. Drafts never get
+// this far, see parsePosts.
+func (p Post) PublishableAt(now time.Time) bool {
+	if p.Unlisted || p.Date.After(now) {
+		return false
+	}
+	return p.Expires.IsZero() || p.Expires.After(now)
+}
+
+// publishablePosts returns the posts that are Publishable.
+func publishablePosts(posts []Post) []Post {
+	return postsWhere(posts, Post.Publishable)
+}
+
+// checkDuplicateTitles reports posts sharing a title, ignoring case, as
+// cfg.DuplicateTitles says. Pages, feeds and structured data all key off
+// the post's path, so these only confuse readers, not the build.
+func checkDuplicateTitles(posts []Post) error {
+	if cfg.DuplicateTitles == "" {
+		return nil
+	}
+	byTitle := map[string][]Post{}
+	var titles []string
+	for _, post := range posts {
+		key := strings.ToLower(strings.TrimSpace(post.Title))
+		if byTitle[key] == nil {
+			titles = append(titles, key)
+		}
+		byTitle[key] = append(byTitle[key], post)
+	}
+	for _, key := range titles {
+		same := byTitle[key]
+		if len(same) < 2 || sameTranslation(same) {
+			continue
+		}
+		files := make([]string, len(same))
+		for i, p := range same {
+			files[i] = filepath.Join(p.Type.Dir, p.Filename)
+		}
+		msg := fmt.Sprintf("%s share the title %q", strings.Join(files, ", "), same[0].Title)
+		if cfg.DuplicateTitles == "error" {
+			return errors.New(msg)
+		}
+		warnf("%s", msg)
+	}
+	return nil
+}
+
+// checkDescriptions warns about publishable posts whose description is
+// missing or outside the configured length, unless their content type has
+// DescriptionOptional.
+func checkDescriptions(posts []Post) {
+	if !cfg.Descriptions.Check {
+		return
+	}
+	minLen, maxLen := cfg.Descriptions.MinLength, cfg.Descriptions.MaxLength
+	for _, post := range publishablePosts(posts) {
+		if post.Type.DescriptionOptional {
+			continue
+		}
+		source := filepath.Join(post.Type.Dir, post.Filename)
+		n := utf8.RuneCountInString(strings.TrimSpace(post.Description))
+		switch {
+		case n == 0:
+			warnf("%s has no description", source)
+		case n < minLen:
+			warnf("%s has a description of %d characters, shorter than %d", source, n, minLen)
+		case n > maxLen:
+			warnf("%s has a description of %d characters, longer than %d", source, n, maxLen)
+		}
+	}
+}
+
+// sameTranslation reports whether posts are all translations of the same
+// content.
+func sameTranslation(posts []Post) bool {
+	for _, p := range posts {
+		if p.TranslationKey == "" || p.TranslationKey != posts[0].TranslationKey {
+			return false
+		}
+	}
+	return true
+}
+
+// postsWhere returns the posts for which keep returns true.
+func postsWhere(posts []Post, keep func(Post) bool) []Post {
+	var kept []Post
+	for _, post := range posts {
+		if keep(post) {
+			kept = append(kept, post)
+		}
+	}
+	return kept
+}
+
+// ShareImage returns the site-relative image for social previews: the
+// cover, else the first content image, else the generated social card, else
+// cfg.DefaultCover.
+func (p Post) ShareImage() string {
+	if p.Cover != "" {
+		return p.Cover
+	}
+	if p.FirstImage != "" {
+		return p.FirstImage
+	}
+	if p.SocialImage != "" {
+		return p.SocialImage
+	}
+	return strings.TrimPrefix(cfg.DefaultCover, "/")
+}
+
+func (p Post) ReadingTimeString() string {
+	return readingTime(p.Words, cfg.ReadingTime)
+}
+
 type IndexData struct {
-	Posts       []Post
+	Posts []Post
+	// Entries are Posts as the index lists them, with series grouped.
+	Entries     []IndexEntry
+	Featured    []Post
 	LastUpdated string
 	GAID        string
+	JSONLD      template.JS
+	// Summaries shows each post's SummaryHTML under its title.
+	Summaries bool
+	// NoPosts is set when there is nothing to list yet, in which case the
+	// index shows partials/no-posts.gohtml and no feeds are written.
+	NoPosts bool
 }
 
 type PostData struct {
@@ -109,79 +585,384 @@ type PostData struct {
 }
 
 func main() {
-	if err := os.MkdirAll("public", 0o755); err != nil {
+	args := os.Args[1:]
+	deploy := len(args) > 0 && args[0] == "deploy"
+	if deploy {
+		args = args[1:]
+	}
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s [deploy] [flags]\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	strict := flags.Bool("strict", false, "fail, and do not deploy, if the build reports warnings")
+	var opts buildOptions
+	flags.BoolVar(&opts.noState, "no-state", false, "ignore the previous build's "+statePath+" and do not write one")
+	flags.IntVar(&opts.jobs, "j", runtime.NumCPU(), "run up to `N` build jobs at once; 1 runs every stage in order")
+	flags.IntVar(&opts.limit, "limit", 0, "build only the `N` newest posts, for faster builds while writing")
+	flags.BoolVar(&verbose, "verbose", false, "report build details, like the template of each page")
+	flags.Parse(args)
+	if flags.NArg() > 0 || opts.limit < 0 || opts.jobs < 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	build(opts)
+
+	if *strict && warnings > 0 {
+		fmt.Fprintf(os.Stderr, "strict: build reported %d warning(s)\n", warnings)
+		os.Exit(1)
+	}
+	if deploy {
+		if err := runDeploy("public"); err != nil {
+			fmt.Fprintf(os.Stderr, "deploy: %v\n", err)
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				os.Exit(exitErr.ExitCode())
+			}
+			os.Exit(1)
+		}
+	}
+}
+
+// buildOptions are the command-line settings of a build.
+type buildOptions struct {
+	// noState skips reading and writing statePath.
+	noState bool
+	// limit, if positive, caps the posts built to the newest limit.
+	limit int
+	// jobs is the most work the build runs at once. See parallel.
+	jobs int
+}
+
+// build generates the whole site into public.
+func build(opts buildOptions) {
+	ctx := withJobs(context.Background(), opts.jobs)
+
+	var err error
+	buildTime, err = sourceDate()
+	if err != nil {
+		panic(err)
+	}
+	cfg, err = loadConfig(configPath)
+	if err != nil {
+		panic(err)
+	}
+
+	configureRawHTML()
+
+	if !opts.noState {
+		prevState = readState(statePath)
+	}
+
+	if err := mkdirOutput("public"); err != nil {
 		panic(err)
 	}
 
-	posts, err := parsePosts("posts")
+	imageFormats = availableImageFormats(cfg.ImageFormats)
+
+	if err := generateBundles("static", "public"); err != nil {
+		panic(err)
+	}
+
+	if cfg.StaticFirst {
+		if err := copyStaticFiles("static", "public"); err != nil {
+			panic(err)
+		}
+	}
+
+	authors, err = loadAuthors(authorsPath)
 	if err != nil {
 		panic(err)
 	}
 
+	// A limited build is partial, so it does not write the state, and
+	// skips the checks that need the whole site.
+	var limit *postLimit
+	if opts.limit > 0 {
+		limit, err = newPostLimit(opts.limit)
+		if err != nil {
+			panic(err)
+		}
+		fmt.Fprintf(os.Stderr, "limit: building %d of %d posts\n", len(limit.keep), limit.total)
+	}
+
+	var posts []Post
+	paths := map[string]string{}
+	for _, ct := range cfg.ContentTypes {
+		typePosts, err := parsePosts(ctx, ct, limit)
+		if err != nil {
+			panic(err)
+		}
+		for _, post := range typePosts {
+			if other, ok := paths[post.Path]; ok {
+				panic(fmt.Errorf("%s and %s are both published at /%s", other, filepath.Join(ct.Dir, post.Filename), post.Path))
+			}
+			paths[post.Path] = filepath.Join(ct.Dir, post.Filename)
+		}
+		posts = append(posts, typePosts...)
+	}
+
 	sort.Slice(posts, func(i, j int) bool {
 		return posts[i].Date.After(posts[j].Date)
 	})
+	setFirstPublished(posts, prevState)
+	checkImages(posts)
+	checkCovers(posts)
+	if limit == nil {
+		checkCanonicals(posts)
+	}
+	if err := checkDuplicateTitles(posts); err != nil {
+		panic(err)
+	}
+	checkDescriptions(posts)
+	linkRelated(posts, limit)
+
+	if err := generateSocialImages(ctx, posts); err != nil {
+		panic(err)
+	}
+
+	if err := generateQRCodes(ctx, posts); err != nil {
+		panic(err)
+	}
+
+	if err := generatePostPages(ctx, posts); err != nil {
+		panic(err)
+	}
+
+	// Every non-draft post gets a page, but only publishable ones are
+	// listed anywhere.
+	listed := publishablePosts(posts)
+	linkTranslations(listed)
+	// Listings put pinned and weighted posts first; feeds and the archive
+	// keep to dates.
+	byDate := postsWhere(listed, func(p Post) bool { return p.Type.Index && p.InIndex })
+	indexed := listingOrder(byDate)
+	inFeeds := postsWhere(listed, func(p Post) bool { return p.Type.Feed && p.InFeed })
+
+	if err := generateIndex(indexed); err != nil {
+		panic(err)
+	}
+
+	if err := generateAuthorPages(indexed); err != nil {
+		panic(err)
+	}
+
+	if err := generateGlossary(indexed); err != nil {
+		panic(err)
+	}
 
-	if err := generatePostPages(posts); err != nil {
+	if err := generateFeaturedPage(indexed); err != nil {
 		panic(err)
 	}
 
-	if err := generateIndex(posts); err != nil {
+	if err := generateAllPage(indexed); err != nil {
 		panic(err)
 	}
 
-	if err := generateFeed(posts); err != nil {
+	if err := generateTaxonomies(byDate); err != nil {
 		panic(err)
 	}
 
-	if err := generateSitemap(posts); err != nil {
+	if err := generateTagsJSON(indexed); err != nil {
 		panic(err)
 	}
 
-	if err := copyStaticFiles("static", "public"); err != nil {
+	if err := generateFeeds(inFeeds); err != nil {
 		panic(err)
 	}
+
+	if err := generateUpdatesFeed(inFeeds); err != nil {
+		panic(err)
+	}
+
+	if err := generateSeriesFeed(inFeeds); err != nil {
+		panic(err)
+	}
+
+	if err := generateOPML(inFeeds); err != nil {
+		panic(err)
+	}
+
+	inSitemap := postsWhere(listed, func(p Post) bool { return p.Type.Sitemap })
+	if err := generateSitemap(inSitemap); err != nil {
+		panic(err)
+	}
+
+	if err := generateSitemapTxt(inSitemap); err != nil {
+		panic(err)
+	}
+
+	redirects, vanished := resolveRedirects(posts, prevState, limit != nil)
+	if err := generateRedirects(redirects); err != nil {
+		panic(err)
+	}
+
+	if !cfg.StaticFirst {
+		if err := copyStaticFiles("static", "public"); err != nil {
+			panic(err)
+		}
+	}
+
+	if err := generateImageVariants(ctx, "static", "public"); err != nil {
+		panic(err)
+	}
+
+	if limit == nil {
+		checkLinks(posts)
+	}
+
+	if err := generateBuildInfo(); err != nil {
+		panic(err)
+	}
+
+	if !opts.noState && limit == nil {
+		sources := []string{configPath, authorsPath, "templates", "static"}
+		for _, ct := range cfg.ContentTypes {
+			sources = append(sources, ct.Dir)
+		}
+		if err := writeState(statePath, posts, redirects, vanished, sources); err != nil {
+			panic(err)
+		}
+	}
 }
 
-func parsePosts(dir string) ([]Post, error) {
+var (
+	// warnings counts the warnf calls, for --strict.
+	warnings   int
+	warningsMu sync.Mutex
+)
+
+// verbose enables verbosef output, with --verbose.
+var verbose bool
+
+// verbosef reports build details when running with --verbose.
+func verbosef(format string, args ...any) {
+	if verbose {
+		fmt.Fprintf(os.Stderr, format+"\n", args...)
+	}
+}
+
+// warnf reports a problem that does not stop the build.
+func warnf(format string, args ...any) {
+	warningsMu.Lock()
+	defer warningsMu.Unlock()
+	warnings++
+	fmt.Fprintf(os.Stderr, "warning: "+format+"\n", args...)
+}
+
+// parsePosts parses the markdown files in the content type's directory,
+// only those limit keeps if it is not nil.
+func parsePosts(ctx context.Context, ct contentType, limit *postLimit) ([]Post, error) {
+	dir := ct.Dir
 	entries, err := os.ReadDir(dir)
 	if err != nil {
 		return nil, err
 	}
 
-	var posts []Post
+	var names []string
 	for _, entry := range entries {
 		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".md") {
 			continue
 		}
+		if limit != nil && !limit.keep[filepath.Join(dir, entry.Name())] {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
 
-		path := filepath.Join(dir, entry.Name())
+	parsed := make([]Post, len(names))
+	err = parallel(ctx, len(names), func(_ context.Context, i int) error {
+		path := filepath.Join(dir, names[i])
 		content, err := os.ReadFile(path)
 		if err != nil {
-			return nil, fmt.Errorf("read post %s: %w", path, err)
+			return fmt.Errorf("read post %s: %w", path, err)
 		}
 
-		post, err := parsePost(entry.Name(), content)
+		post, err := parsePost(ct, names[i], content)
 		if err != nil {
-			return nil, fmt.Errorf("parse post %s: %w", entry.Name(), err)
+			return fmt.Errorf("parse post %s: %w", names[i], err)
 		}
-
-		posts = append(posts, post)
+		parsed[i] = post
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return posts, nil
+	return postsWhere(parsed, func(p Post) bool { return !p.Draft }), nil
 }
 
 type postMeta struct {
 	Title       string `yaml:"title"`
 	Date        string `yaml:"date"`
 	Description string `yaml:"description"`
-	Cover       string `yaml:"cover"`
+	Updated     string `yaml:"updated"`
+	UpdateNote  string `yaml:"updateNote"`
+	// Layout names the template to render the post with, by its path under
+	// templates/, e.g. "layouts/wide.gohtml". Defaults to post.gohtml.
+	Layout string `yaml:"layout"`
+	// ShowTime includes the time of day from Date in DateString.
+	ShowTime bool   `yaml:"showTime"`
+	Cover    string `yaml:"cover"`
+	// SitemapPriority overrides the age-based sitemap priority.
+	SitemapPriority *float64 `yaml:"sitemapPriority"`
+	// Author and Authors are keys into authors.yaml.
+	Author  string   `yaml:"author"`
+	Authors []string `yaml:"authors"`
+	// Featured posts are also listed on featured.html and the index.
+	Featured       bool `yaml:"featured"`
+	FeaturedWeight int  `yaml:"featuredWeight"`
+	// Pinned and Weight order the listings; see listingOrder.
+	Pinned bool `yaml:"pinned"`
+	Weight int  `yaml:"weight"`
+	// Feed and InIndex set to false keep a listed post out of the feeds,
+	// or out of the index and the other listing pages.
+	Feed    *bool `yaml:"feed"`
+	InIndex *bool `yaml:"inIndex"`
+	// SummaryFrom names the heading whose section is the summary,
+	// overriding the summaryFrom config.
+	SummaryFrom string `yaml:"summaryFrom"`
+	// Lang is the post's language code, defaulting to the language config.
+	// Posts sharing a TranslationKey are translations of each other.
+	Lang           string `yaml:"lang"`
+	TranslationKey string `yaml:"translationKey"`
+	// Draft posts are not built at all. Unlisted, future and expired posts
+	// get a page but are left out of every listing; see Post.Publishable.
+	Draft    bool `yaml:"draft"`
+	Unlisted bool `yaml:"unlisted"`
+	// TOC shows a table of contents where the template puts it. A [[TOC]]
+	// or {{< toc >}} line places it in the content instead.
+	TOC bool `yaml:"toc"`
+	// Related lists the slugs of the posts shown as related, replacing
+	// the ones picked by shared terms.
+	Related []string `yaml:"related"`
+	// Canonical points search engines at another URL for the post, for
+	// content first published elsewhere. Relative values are resolved
+	// against siteURL.
+	Canonical string `yaml:"canonical"`
+	// EditLink set to false hides the edit link of editURLTemplate.
+	EditLink *bool `yaml:"editLink"`
+	// Printable posts link the print stylesheet and show a Print button.
+	Printable  bool   `yaml:"printable"`
+	ExpiryDate string `yaml:"expiryDate"`
 }
 
-func parsePost(filename string, content []byte) (Post, error) {
-	ctx := parser.NewContext()
+func parsePost(ct contentType, filename string, content []byte) (Post, error) {
+	content, err := decodeContent(filename, content)
+	if err != nil {
+		return Post{}, err
+	}
+	ctx := parser.NewContext(parser.WithIDs(newHeadingIDs()))
 	doc := md.Parser().Parse(text.NewReader(content), parser.WithContext(ctx))
+	if expanded, ok := expandSiteVars(filename, content, doc); ok {
+		content = expanded
+		ctx = parser.NewContext(parser.WithIDs(newHeadingIDs()))
+		doc = md.Parser().Parse(text.NewReader(content), parser.WithContext(ctx))
+	}
+	if err := checkFrontMatterLeak(doc, content); err != nil {
+		return Post{}, fmt.Errorf("%s: %w", filename, err)
+	}
 
 	d := frontmatter.Get(ctx)
 	if d == nil {
@@ -197,17 +978,112 @@ func parsePost(filename string, content []byte) (Post, error) {
 		return Post{}, fmt.Errorf("missing title in %s", filename)
 	}
 
-	date, err := time.Parse(dateLayout, meta.Date)
+	if meta.SitemapPriority != nil {
+		if err := validatePriority(*meta.SitemapPriority); err != nil {
+			return Post{}, fmt.Errorf("invalid sitemapPriority in %s: %w", filename, err)
+		}
+	}
+
+	if meta.Layout != "" && htmlTemplates.Lookup(meta.Layout) == nil {
+		return Post{}, fmt.Errorf("unknown layout %q in %s: no template by that name", meta.Layout, filename)
+	}
+
+	if err := checkShortcodes(doc, filename); err != nil {
+		return Post{}, err
+	}
+
+	slug, fileDate := postSlug(filename)
+	if meta.Date == "" {
+		meta.Date = fileDate
+	}
+	date, err := parseDate(meta.Date)
 	if err != nil {
 		return Post{}, fmt.Errorf("invalid date %q in %s: %w", meta.Date, filename, err)
 	}
 
+	var updated time.Time
+	if meta.Updated != "" {
+		updated, err = parseDate(meta.Updated)
+		if err != nil {
+			return Post{}, fmt.Errorf("invalid updated date %q in %s: %w", meta.Updated, filename, err)
+		}
+		if updated.Before(date) {
+			return Post{}, fmt.Errorf("updated date %s is before date %s in %s", meta.Updated, meta.Date, filename)
+		}
+	}
+
+	var expires time.Time
+	if meta.ExpiryDate != "" {
+		expires, err = parseDate(meta.ExpiryDate)
+		if err != nil {
+			return Post{}, fmt.Errorf("invalid expiryDate %q in %s: %w", meta.ExpiryDate, filename, err)
+		}
+		if !expires.After(date) {
+			return Post{}, fmt.Errorf("expiryDate %s is not after date %s in %s", meta.ExpiryDate, meta.Date, filename)
+		}
+	}
+
+	sections := collectSections(doc, content)
+	inlineTOC := placeTOC(doc, content, sections, filename)
+	hasMath, hasMermaid := contentFeatures(doc, content)
+	terms := collectTerms(doc)
+
 	var buf bytes.Buffer
 	if err := md.Renderer().Render(&buf, content, doc); err != nil {
 		return Post{}, err
 	}
 
-	slug := strings.TrimSuffix(filename, ".md")
+	summaryFrom := cfg.SummaryFrom
+	if meta.SummaryFrom != "" {
+		summaryFrom = meta.SummaryFrom
+	}
+	summary, err := renderSummary(doc, content, summaryFrom, meta.Description)
+	if err != nil {
+		return Post{}, err
+	}
+
+	lang := meta.Lang
+	if lang == "" {
+		lang = cfg.Language
+	}
+
+	authorKeys := meta.Authors
+	if meta.Author != "" {
+		authorKeys = append([]string{meta.Author}, authorKeys...)
+	}
+
+	// Terms may be a single string or a list, which postMeta cannot
+	// express for both YAML and TOML, so they are read from a generic
+	// decoding.
+	var raw map[string]any
+	if err := d.Decode(&raw); err != nil {
+		return Post{}, fmt.Errorf("invalid front matter in %s: %w", filename, err)
+	}
+	tags, err := termList(raw, "tags")
+	if err != nil {
+		return Post{}, fmt.Errorf("invalid front matter in %s: %w", filename, err)
+	}
+	taxonomies, err := taxonomyTerms(raw)
+	if err != nil {
+		return Post{}, fmt.Errorf("invalid front matter in %s: %w", filename, err)
+	}
+	checkTaxonomyValues(filename, taxonomies)
+
+	canonical, err := normalizeCanonical(meta.Canonical)
+	if err != nil {
+		return Post{}, fmt.Errorf("invalid canonical in %s: %w", filename, err)
+	}
+	path := ct.URLPrefix + slug + ".html"
+	pageURL := cfg.SiteURL + "/" + path
+	if canonical != "" {
+		pageURL = canonical
+	}
+
+	prefixAnchors(doc, slug+"-")
+	var allBuf bytes.Buffer
+	if err := md.Renderer().Render(&allBuf, content, doc); err != nil {
+		return Post{}, err
+	}
 
 	ld := jsonLD{
 		Context:       "https://schema.org",
@@ -215,10 +1091,11 @@ func parsePost(filename string, content []byte) (Post, error) {
 		Headline:      meta.Title,
 		Description:   meta.Description,
 		DatePublished: date.Format(time.RFC3339),
+		DateModified:  formatOptionalTime(updated, time.RFC3339),
 		Author: jsonLDPerson{
 			Type: "Person",
 			Name: "Özgür Tanrıverdi",
-			URL:  siteURL,
+			URL:  cfg.SiteURL,
 		},
 		Publisher: jsonLDPerson{
 			Type: "Person",
@@ -226,86 +1103,274 @@ func parsePost(filename string, content []byte) (Post, error) {
 		},
 		MainEntityOfPage: jsonLDPage{
 			Type: "WebPage",
-			ID:   siteURL + "/" + slug + ".html",
+			ID:   pageURL,
 		},
 	}
+	var coverSources []imageSource
+	firstImg := firstImage(doc, path)
 	if meta.Cover != "" {
-		ld.Image = siteURL + "/" + meta.Cover
+		ld.Image = cfg.SiteURL + "/" + meta.Cover
+		coverSources = pictureSources("/" + meta.Cover)
+	} else if firstImg != "" {
+		ld.Image = cfg.SiteURL + "/" + firstImg
 	}
 	jsonLDBytes, _ := json.Marshal(ld)
 
 	return Post{
-		Title:       meta.Title,
-		Date:        date,
-		Description: meta.Description,
-		Cover:       meta.Cover,
-		Slug:        slug,
-		Content:     template.HTML(buf.String()),
-		JSONLD:      template.JS(jsonLDBytes),
+		Title:          meta.Title,
+		Date:           date,
+		Updated:        updated,
+		UpdateNote:     meta.UpdateNote,
+		ShowTime:       meta.ShowTime,
+		Description:    meta.Description,
+		Cover:          meta.Cover,
+		CoverSources:   coverSources,
+		FirstImage:     firstImg,
+		Slug:           slug,
+		Filename:       filename,
+		Canonical:      canonical,
+		RelatedSlugs:   meta.Related,
+		EditLink:       meta.EditLink == nil || *meta.EditLink,
+		Path:           path,
+		Type:           ct,
+		Layout:         meta.Layout,
+		Authors:        resolveAuthors(filename, authorKeys),
+		Words:          countWords(doc, content),
+		Sections:       sections,
+		TOC:            meta.TOC,
+		InlineTOC:      inlineTOC,
+		Priority:       meta.SitemapPriority,
+		Featured:       meta.Featured,
+		FeaturedWeight: meta.FeaturedWeight,
+		Pinned:         meta.Pinned,
+		Weight:         meta.Weight,
+		InFeed:         meta.Feed == nil || *meta.Feed,
+		InIndex:        meta.InIndex == nil || *meta.InIndex,
+		Terms:          terms,
+		Tags:           tags,
+		Taxonomies:     taxonomies,
+		SummaryHTML:    summary,
+		SummaryText:    plainText(summary),
+		Lang:           lang,
+		TranslationKey: meta.TranslationKey,
+		Draft:          meta.Draft,
+		Unlisted:       meta.Unlisted,
+		Printable:      meta.Printable,
+		HasMath:        hasMath,
+		HasMermaid:     hasMermaid,
+		HasCopyButtons: hasCopyButtons(doc),
+		Expires:        expires,
+		Content:        template.HTML(buf.String()),
+		AllContent:     template.HTML(allBuf.String()),
+		JSONLD:         template.JS(jsonLDBytes),
 	}, nil
 }
 
-func generatePostPages(posts []Post) error {
-	for _, post := range posts {
-		path := filepath.Join("public", post.Slug+".html")
-		f, err := os.Create(path)
+// filenameDatePattern matches post filenames, without .md, that start
+// with a YYYY-MM-DD- date prefix, capturing the date and the rest.
+var filenameDatePattern = regexp.MustCompile(`^(\d{4}-\d{2}-\d{2})-(.+)$`)
+
+// postSlug returns the slug of a post file. With cfg.FilenameDates, a
+// valid date prefix is left out of the slug and returned as date.
+func postSlug(filename string) (slug, date string) {
+	slug = strings.TrimSuffix(filename, ".md")
+	if !cfg.FilenameDates {
+		return slug, ""
+	}
+	m := filenameDatePattern.FindStringSubmatch(slug)
+	if m == nil {
+		return slug, ""
+	}
+	if _, err := time.Parse(dateLayout, m[1]); err != nil {
+		return slug, ""
+	}
+	return m[2], m[1]
+}
+
+// dateLayouts are the accepted formats of front matter dates, tried in
+// order.
+var dateLayouts = []string{
+	dateLayout,
+	"2006-01-02 15:04",
+	"2006-01-02T15:04",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+}
+
+// parseDate parses a front matter date with or without a time of day.
+// Values without a UTC offset are read in cfg.Timezone.
+func parseDate(s string) (time.Time, error) {
+	var firstErr error
+	for _, layout := range dateLayouts {
+		t, err := time.ParseInLocation(layout, s, cfg.location)
+		if err == nil {
+			return t, nil
+		}
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+	return time.Time{}, firstErr
+}
+
+func generatePostPages(ctx context.Context, posts []Post) error {
+	return parallel(ctx, len(posts), func(_ context.Context, i int) error {
+		post := posts[i]
+		path := filepath.Join("public", filepath.FromSlash(post.Path))
+		if err := mkdirOutput(filepath.Dir(path)); err != nil {
+			return fmt.Errorf("create post dir for %s: %w", path, err)
+		}
+		f, err := createOutput(path)
 		if err != nil {
 			return fmt.Errorf("create post page %s: %w", path, err)
 		}
+		defer f.Close()
 
-		if err := postTmpl.Execute(f, PostData{Post: post, GAID: gaID}); err != nil {
-			f.Close()
+		tmpl, err := postTemplate(post)
+		if err != nil {
+			return err
+		}
+		verbosef("%s: rendering with %s", post.Path, tmpl.Name())
+
+		if err := tmpl.Execute(f, PostData{Post: post, GAID: gaID}); err != nil {
 			return fmt.Errorf("render post %s: %w", post.Slug, err)
 		}
-		f.Close()
-	}
+		return nil
+	})
+}
 
-	return nil
+// postTemplate resolves the template of a post page, as the first that
+// exists of its layout, its type's template, the template named after its
+// type, like notes.gohtml, and post.gohtml. parsePost has already rejected
+// layouts that do not exist.
+func postTemplate(post Post) (*template.Template, error) {
+	var tried []string
+	for _, name := range []string{post.Layout, post.Type.Template, post.Type.Name + ".gohtml", "post.gohtml"} {
+		if name == "" {
+			continue
+		}
+		if tmpl := htmlTemplates.Lookup(name); tmpl != nil {
+			return tmpl, nil
+		}
+		tried = append(tried, name)
+	}
+	return nil, fmt.Errorf("no template for %s: none of %s exist", post.Path, strings.Join(tried, ", "))
 }
 
 func generateIndex(posts []Post) error {
-	f, err := os.Create("public/index.html")
+	f, err := createOutput("public/index.html")
 	if err != nil {
 		return fmt.Errorf("create index: %w", err)
 	}
 	defer f.Close()
 
-	if err := indexTmpl.Execute(f, IndexData{Posts: posts, GAID: gaID}); err != nil {
+	data := IndexData{Posts: posts, Entries: indexEntries(posts), Featured: featuredPosts(posts), GAID: gaID, Summaries: cfg.IndexSummaries, NoPosts: len(posts) == 0}
+	if cfg.SearchURLTemplate != "" {
+		target := cfg.SearchURLTemplate
+		if strings.HasPrefix(target, "/") {
+			target = cfg.SiteURL + target
+		}
+		jsonLDBytes, err := json.Marshal(jsonLDWebSite{
+			Context: "https://schema.org",
+			Type:    "WebSite",
+			Name:    "Özgür Tanrıverdi (otrv)",
+			URL:     cfg.SiteURL,
+			PotentialAction: jsonLDSearchAction{
+				Type:       "SearchAction",
+				Target:     target,
+				QueryInput: "required name=search_term_string",
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("marshal index json-ld: %w", err)
+		}
+		data.JSONLD = template.JS(jsonLDBytes)
+	}
+
+	if err := indexTmpl.Execute(f, data); err != nil {
 		return fmt.Errorf("render index: %w", err)
 	}
 	return nil
 }
 
-type FeedData struct {
-	Updated string
-	Posts   []Post
+// featuredPosts returns the featured posts by descending FeaturedWeight,
+// newest first within the same weight. Pinned and Weight do not apply.
+func featuredPosts(posts []Post) []Post {
+	var featured []Post
+	for _, post := range posts {
+		if post.Featured {
+			featured = append(featured, post)
+		}
+	}
+	sort.SliceStable(featured, func(i, j int) bool {
+		if featured[i].FeaturedWeight != featured[j].FeaturedWeight {
+			return featured[i].FeaturedWeight > featured[j].FeaturedWeight
+		}
+		return featured[i].Date.After(featured[j].Date)
+	})
+	return featured
+}
+
+// listingOrder returns posts in the order of the index and the other
+// listing pages: pinned posts first, then by descending Weight, then
+// newest first. Weight orders pinned posts among themselves too, and a
+// negative weight sinks a post below those without one. posts must be
+// sorted newest first.
+func listingOrder(posts []Post) []Post {
+	ordered := slices.Clone(posts)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Pinned != ordered[j].Pinned {
+			return ordered[i].Pinned
+		}
+		return ordered[i].Weight > ordered[j].Weight
+	})
+	return ordered
 }
 
-func generateFeed(posts []Post) error {
-	f, err := os.Create("public/feed.xml")
+func generateFeaturedPage(posts []Post) error {
+	f, err := createOutput("public/featured.html")
 	if err != nil {
-		return fmt.Errorf("create feed: %w", err)
+		return fmt.Errorf("create featured page: %w", err)
 	}
 	defer f.Close()
 
-	var updated time.Time
-	if len(posts) > 0 {
-		updated = posts[0].Date
-	} else {
-		updated = time.Now()
+	if err := featuredTmpl.Execute(f, IndexData{Posts: featuredPosts(posts), GAID: gaID}); err != nil {
+		return fmt.Errorf("render featured page: %w", err)
 	}
+	return nil
+}
 
-	if err := feedTmpl.ExecuteTemplate(f, "feed.xml", FeedData{
-		Updated: updated.Format(time.RFC3339),
-		Posts:   posts,
-	}); err != nil {
-		return fmt.Errorf("render feed: %w", err)
+func generateAllPage(posts []Post) error {
+	f, err := createOutput("public/all.html")
+	if err != nil {
+		return fmt.Errorf("create all page: %w", err)
+	}
+	defer f.Close()
+
+	// Oldest first whatever the listing order, which pins posts.
+	chronological := slices.Clone(posts)
+	sort.SliceStable(chronological, func(i, j int) bool {
+		return chronological[i].Date.Before(chronological[j].Date)
+	})
+
+	if err := allTmpl.Execute(f, IndexData{Posts: chronological, GAID: gaID}); err != nil {
+		return fmt.Errorf("render all page: %w", err)
 	}
 	return nil
 }
 
+type SitemapData struct {
+	Posts            []Post
+	LastUpdated      string
+	AlternateURL     string
+	HomepagePriority string
+	// XHTML declares the xhtml namespace used by alternate links.
+	XHTML bool
+}
+
 func generateSitemap(posts []Post) error {
-	f, err := os.Create("public/sitemap.xml")
+	f, err := createOutput("public/sitemap.xml")
 	if err != nil {
 		return fmt.Errorf("create sitemap: %w", err)
 	}
@@ -318,34 +1383,66 @@ func generateSitemap(posts []Post) error {
 		lastUpdated = time.Now().Format(dateLayout)
 	}
 
-	if err := sitemapTmpl.ExecuteTemplate(f, "sitemap.xml", IndexData{
-		Posts:       posts,
-		LastUpdated: lastUpdated,
+	if err := sitemapTmpl.ExecuteTemplate(f, "sitemap.xml", SitemapData{
+		Posts:            posts,
+		LastUpdated:      lastUpdated,
+		AlternateURL:     cfg.AlternateSiteURL,
+		HomepagePriority: formatPriority(cfg.Sitemap.HomepagePriority),
+		XHTML:            cfg.AlternateSiteURL != "" || hasTranslations(posts),
 	}); err != nil {
 		return fmt.Errorf("render sitemap: %w", err)
 	}
 	return nil
 }
 
-func copyStaticFiles(srcDir, dstDir string) error {
-	entries, err := os.ReadDir(srcDir)
-	if err != nil {
-		return fmt.Errorf("read static dir %s: %w", srcDir, err)
+// generateSitemapTxt writes sitemap.txt, the homepage and every post of the
+// XML sitemap as absolute URLs, one per line. It is only written when
+// cfg.Sitemap.Text is set.
+func generateSitemapTxt(posts []Post) error {
+	if !cfg.Sitemap.Text {
+		return nil
 	}
 
-	for _, entry := range entries {
+	var b strings.Builder
+	b.WriteString(cfg.SiteURL + "/\n")
+	for _, post := range posts {
+		b.WriteString(post.URL() + "\n")
+	}
+	if err := writeOutput("public/sitemap.txt", []byte(b.String())); err != nil {
+		return fmt.Errorf("write sitemap.txt: %w", err)
+	}
+	return nil
+}
+
+func copyStaticFiles(srcDir, dstDir string) error {
+	return filepath.WalkDir(srcDir, func(src string, entry os.DirEntry, err error) error {
+		if err != nil {
+			return fmt.Errorf("read static dir %s: %w", src, err)
+		}
+
+		rel, err := filepath.Rel(srcDir, src)
+		if err != nil {
+			return err
+		}
+		dst := filepath.Join(dstDir, rel)
+
+		if bundledFiles[src] {
+			return nil
+		}
 		if entry.IsDir() {
-			continue
+			if err := mkdirOutput(dst); err != nil {
+				return fmt.Errorf("create static dir %s: %w", dst, err)
+			}
+			return nil
 		}
-		src := filepath.Join(srcDir, entry.Name())
-		dst := filepath.Join(dstDir, entry.Name())
+
 		content, err := os.ReadFile(src)
 		if err != nil {
 			return fmt.Errorf("read static file %s: %w", src, err)
 		}
-		if err := os.WriteFile(dst, content, 0o644); err != nil {
+		if err := writeOutput(dst, content); err != nil {
 			return fmt.Errorf("write static file %s: %w", dst, err)
 		}
-	}
-	return nil
+		return nil
+	})
 }
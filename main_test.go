@@ -0,0 +1,396 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPublishedOutputs builds a site of one post in each publishing state
+// and checks that every output lists exactly the publishable ones, while
+// every post but the draft gets a page.
+func TestPublishedOutputs(t *testing.T) {
+	posts := map[string]string{
+		"published": "date: 2024-01-01",
+		"draft":     "date: 2024-01-01\ndraft: true",
+		"future":    "date: 2999-01-01",
+		"expired":   "date: 2024-01-01\nexpiryDate: 2024-06-01",
+		"unlisted":  "date: 2024-01-01\nunlisted: true",
+	}
+	buildSite(t, "", posts)
+
+	outputs := []string{
+		"index.html",
+		"all.html",
+		"feed.xml",
+		"sitemap.xml",
+		"tags/shared.html",
+	}
+	for _, out := range outputs {
+		content, err := os.ReadFile(filepath.Join("public", out))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for slug := range posts {
+			want := slug == "published"
+			if got := strings.Contains(string(content), "/"+slug+".html"); got != want {
+				t.Errorf("%s lists %s: %v, want %v", out, slug, got, want)
+			}
+		}
+	}
+	tags, err := os.ReadFile("public/api/tags.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `[{"tag":"shared","slug":"shared","count":1}]` + "\n"; string(tags) != want {
+		t.Errorf("api/tags.json = %s, want %s", tags, want)
+	}
+	for slug := range posts {
+		_, err := os.Stat(filepath.Join("public", slug+".html"))
+		if got, want := err == nil, slug != "draft"; got != want {
+			t.Errorf("page for %s written: %v, want %v", slug, got, want)
+		}
+	}
+}
+
+// buildSite builds a site from config.yaml content config and posts, the
+// front matter of each by slug, less its title, which is the slug, and a
+// "shared" tag. The test then runs in the site's directory.
+func buildSite(t *testing.T, config string, posts map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	for _, sub := range []string{"posts", "static"} {
+		if err := os.Mkdir(filepath.Join(dir, sub), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if config != "" {
+		if err := os.WriteFile(filepath.Join(dir, configPath), []byte(config), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for slug, meta := range posts {
+		content := "---\ntitle: " + slug + "\n" + meta + "\ntags: [shared]\n---\n\nBody.\n"
+		if err := os.WriteFile(filepath.Join(dir, "posts", slug+".md"), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	t.Chdir(dir)
+	build(buildOptions{noState: true, jobs: 1})
+}
+
+func TestCheckDescriptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		optional    bool
+		unlisted    bool
+		warn        bool
+	}{
+		{"missing", "", false, false, true},
+		{"blank", "   ", false, false, true},
+		{"too short", strings.Repeat("a", 49), false, false, true},
+		{"shortest", strings.Repeat("a", 50), false, false, false},
+		{"longest", strings.Repeat("a", 160), false, false, false},
+		{"too long", strings.Repeat("a", 161), false, false, true},
+		{"counted in characters", strings.Repeat("é", 160), false, false, false},
+		{"padded", " " + strings.Repeat("a", 160) + " ", false, false, false},
+		{"optional", "", true, false, false},
+		{"unlisted", "", false, true, false},
+	}
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	cfg.Descriptions = descriptionsConfig{Check: true, MinLength: 50, MaxLength: 160}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := Post{
+				Title:       tt.name,
+				Filename:    "post.md",
+				Date:        time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+				Description: tt.description,
+				Unlisted:    tt.unlisted,
+				Type:        contentType{Dir: "posts", DescriptionOptional: tt.optional},
+			}
+			before := warnings
+			checkDescriptions([]Post{post})
+			if got := warnings > before; got != tt.warn {
+				t.Errorf("warned: %v, want %v", got, tt.warn)
+			}
+		})
+	}
+}
+
+// useDefaultConfig sets cfg to the defaults of a site without a config.yaml
+// until the test ends.
+func useDefaultConfig(t *testing.T) {
+	t.Helper()
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	c, err := loadConfig(filepath.Join(t.TempDir(), configPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg = c
+}
+
+func TestPostLayout(t *testing.T) {
+	tests := []struct {
+		layout  string
+		want    string
+		wantErr bool
+	}{
+		{"", "post.gohtml", false},
+		{"featured.gohtml", "featured.gohtml", false},
+		{"layouts/missing.gohtml", "", true},
+	}
+	useDefaultConfig(t)
+	for _, tt := range tests {
+		t.Run(tt.layout, func(t *testing.T) {
+			content := "---\ntitle: x\ndate: 2024-01-01\nlayout: " + tt.layout + "\n---\n\nBody.\n"
+			post, err := parsePost(cfg.ContentTypes[0], "x.md", []byte(content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePost error = %v, want error: %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			tmpl, err := postTemplate(post)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tmpl.Name() != tt.want {
+				t.Errorf("template = %s, want %s", tmpl.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestEditURL(t *testing.T) {
+	tests := []struct {
+		template string
+		dir      string
+		filename string
+		want     string
+	}{
+		{"https://example.com/edit/{path}", "posts", "hello.md", "https://example.com/edit/posts/hello.md"},
+		{"https://example.com/edit/{path}", "posts/2024", "hello world.md", "https://example.com/edit/posts/2024/hello%20world.md"},
+		{"https://example.com/edit/{filename}", "posts", "c#?.md", "https://example.com/edit/c%23%3F.md"},
+		{"https://example.com/edit/{path}", "posts", "café.md", "https://example.com/edit/posts/caf%C3%A9.md"},
+	}
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			cfg.EditURLTemplate = tt.template
+			post := Post{Filename: tt.filename, EditLink: true, Type: contentType{Dir: tt.dir}}
+			if got := post.EditURL(); got != tt.want {
+				t.Errorf("EditURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFeaturedPosts(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name  string
+		posts []Post
+		want  []string
+	}{
+		{"none", nil, nil},
+		{"none featured", []Post{{Title: "a", Date: day(1)}}, nil},
+		{"by date", []Post{
+			{Title: "new", Date: day(2), Featured: true},
+			{Title: "plain", Date: day(3)},
+			{Title: "old", Date: day(1), Featured: true},
+		}, []string{"new", "old"}},
+		{"by weight, then date", []Post{
+			{Title: "new", Date: day(3), Featured: true},
+			{Title: "heavy", Date: day(1), Featured: true, FeaturedWeight: 2},
+			{Title: "old", Date: day(2), Featured: true},
+			{Title: "pinned", Date: day(4), Pinned: true},
+		}, []string{"heavy", "new", "old"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range featuredPosts(tt.posts) {
+				got = append(got, p.Title)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("featuredPosts = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNothingFeatured checks that a site without featured posts still gets
+// a featured page, saying so, and no Featured section on the index.
+func TestNothingFeatured(t *testing.T) {
+	buildSite(t, "", map[string]string{"plain": "date: 2024-01-01"})
+	featured, err := os.ReadFile("public/featured.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(featured), "Nothing featured yet") || strings.Contains(string(featured), "/plain.html") {
+		t.Errorf("featured.html does not say nothing is featured:\n%s", featured)
+	}
+	index, err := os.ReadFile("public/index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(index), `id="featured"`) {
+		t.Errorf("index.html has a Featured section:\n%s", index)
+	}
+}
+
+func TestIsStaleAt(t *testing.T) {
+	date := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		years int
+		now   time.Time
+		want  bool
+	}{
+		{"disabled", 0, date.AddDate(50, 0, 0), false},
+		{"younger", 2, date.AddDate(1, 11, 0), false},
+		{"exactly the threshold", 2, date.AddDate(2, 0, 0), false},
+		{"just past the threshold", 2, date.AddDate(2, 0, 0).Add(time.Second), true},
+		{"older", 2, date.AddDate(5, 0, 0), true},
+	}
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.StaleAfterYears = tt.years
+			if got := (Post{Date: date}).IsStaleAt(tt.now); got != tt.want {
+				t.Errorf("IsStaleAt(%s) with staleAfterYears %d = %v, want %v", tt.now.Format(time.DateOnly), tt.years, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestNoPosts builds a site with an empty posts directory and checks that
+// the index says so and that no feeds are written yet.
+func TestNoPosts(t *testing.T) {
+	buildSite(t, "", nil)
+	index, err := os.ReadFile("public/index.html")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(index), `class="no-posts"`) {
+		t.Errorf("index.html has no no-posts section:\n%s", index)
+	}
+	if strings.Contains(string(index), "application/atom+xml") {
+		t.Errorf("index.html advertises a feed:\n%s", index)
+	}
+	for _, out := range []string{"feed.xml", "feeds.opml"} {
+		if _, err := os.Stat(filepath.Join("public", out)); err == nil {
+			t.Errorf("%s written without posts", out)
+		}
+	}
+	if _, err := os.Stat("public/sitemap.xml"); err != nil {
+		t.Errorf("sitemap.xml not written: %v", err)
+	}
+}
+
+func TestDuplicateTitles(t *testing.T) {
+	tests := []struct {
+		mode    string
+		warn    bool
+		wantErr bool
+	}{
+		{"", false, false},
+		{"warn", true, false},
+		{"error", false, true},
+	}
+	useDefaultConfig(t)
+	var posts []Post
+	for _, file := range []string{"first.md", "second.md"} {
+		post, err := parsePost(cfg.ContentTypes[0], file, []byte("---\ntitle: Same Title\ndate: 2024-01-01\n---\n\nBody.\n"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		posts = append(posts, post)
+	}
+	if posts[0].Path == posts[1].Path || posts[0].JSONLD == posts[1].JSONLD {
+		t.Errorf("posts sharing a title share a path or structured data: %s, %s", posts[0].JSONLD, posts[1].JSONLD)
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			cfg.DuplicateTitles = tt.mode
+			before := warnings
+			err := checkDuplicateTitles(posts)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkDuplicateTitles error = %v, want error: %v", err, tt.wantErr)
+			}
+			if got := warnings > before; got != tt.warn {
+				t.Errorf("warned: %v, want %v", got, tt.warn)
+			}
+		})
+	}
+	t.Run("translations", func(t *testing.T) {
+		cfg.DuplicateTitles = "error"
+		translated := slices.Clone(posts)
+		for i := range translated {
+			translated[i].TranslationKey = "same"
+		}
+		if err := checkDuplicateTitles(translated); err != nil {
+			t.Errorf("translations sharing a title: %v", err)
+		}
+	})
+	t.Run("case and spacing", func(t *testing.T) {
+		cfg.DuplicateTitles = "error"
+		differing := slices.Clone(posts)
+		differing[1].Title = " same title "
+		if err := checkDuplicateTitles(differing); err == nil {
+			t.Error("titles differing in case and spacing not reported")
+		}
+	})
+}
+
+func TestFilenameDates(t *testing.T) {
+	tests := []struct {
+		name          string
+		filenameDates bool
+		filename      string
+		date          string
+		wantSlug      string
+		wantDate      string
+		wantErr       bool
+	}{
+		{"prefixed", true, "2024-03-15-title.md", "", "title", "2024-03-15", false},
+		{"front matter wins", true, "2024-03-15-title.md", "2023-01-02", "title", "2023-01-02", false},
+		{"unprefixed", true, "title.md", "2023-01-02", "title", "2023-01-02", false},
+		{"unprefixed without date", true, "title.md", "", "", "", true},
+		{"invalid prefix date", true, "2024-13-45-title.md", "2023-01-02", "2024-13-45-title", "2023-01-02", false},
+		{"prefix only", true, "2024-03-15.md", "2023-01-02", "2024-03-15", "2023-01-02", false},
+		{"disabled", false, "2024-03-15-title.md", "2023-01-02", "2024-03-15-title", "2023-01-02", false},
+		{"disabled without date", false, "2024-03-15-title.md", "", "", "", true},
+	}
+	useDefaultConfig(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.FilenameDates = tt.filenameDates
+			meta := ""
+			if tt.date != "" {
+				meta = "date: " + tt.date + "\n"
+			}
+			post, err := parsePost(cfg.ContentTypes[0], tt.filename, []byte("---\ntitle: x\n"+meta+"---\n\nBody.\n"))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePost(%s) error = %v, want error: %v", tt.filename, err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if post.Slug != tt.wantSlug || post.Date.Format(dateLayout) != tt.wantDate {
+				t.Errorf("parsePost(%s) = slug %q, date %s, want %q, %s", tt.filename, post.Slug, post.Date.Format(dateLayout), tt.wantSlug, tt.wantDate)
+			}
+		})
+	}
+}
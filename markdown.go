@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"go.abhg.dev/goldmark/frontmatter"
+)
+
+// pathRewriter rewrites relative link and image destinations according to
+// cfg.PathRewrites so posts can reference files by their authoring path.
+type pathRewriter struct{}
+
+func (pathRewriter) Transform(doc *ast.Document, _ text.Reader, _ parser.Context) {
+	if len(cfg.PathRewrites) == 0 {
+		return
+	}
+
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Link:
+			n.Destination = []byte(rewritePath(string(n.Destination)))
+		case *ast.Image:
+			n.Destination = []byte(rewritePath(string(n.Destination)))
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// rewritePath applies the longest matching prefix from cfg.PathRewrites.
+// Absolute paths, anchors and URLs with a scheme are returned unchanged.
+func rewritePath(dest string) string {
+	if !isRelativePath(dest) {
+		return dest
+	}
+
+	var from string
+	for prefix := range cfg.PathRewrites {
+		if strings.HasPrefix(dest, prefix) && len(prefix) > len(from) {
+			from = prefix
+		}
+	}
+	if from == "" {
+		return dest
+	}
+	return cfg.PathRewrites[from] + strings.TrimPrefix(dest, from)
+}
+
+func isRelativePath(dest string) bool {
+	if dest == "" || strings.HasPrefix(dest, "/") || strings.HasPrefix(dest, "#") {
+		return false
+	}
+	if i := strings.IndexAny(dest, ":/?#"); i >= 0 && dest[i] == ':' {
+		return false
+	}
+	return true
+}
+
+// countWords counts the words in the document's text and code blocks.
+func countWords(doc ast.Node, source []byte) int {
+	var words int
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Text:
+			words += len(bytes.Fields(n.Segment.Value(source)))
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				seg := lines.At(i)
+				words += len(bytes.Fields(seg.Value(source)))
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return words
+}
+
+// prefixAnchors prefixes every heading ID, term ID, footnote ID and
+// in-page link fragment in doc with prefix.
+func prefixAnchors(doc ast.Node, prefix string) {
+	doc.SetAttributeString(footnotePrefixAttr, []byte(prefix))
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Heading, *shortcodeNode:
+			if id, ok := n.AttributeString("id"); ok {
+				n.SetAttributeString("id", append([]byte(prefix), id.([]byte)...))
+			}
+		case *ast.Link:
+			if bytes.HasPrefix(n.Destination, []byte("#")) {
+				n.Destination = append([]byte("#"+prefix), n.Destination[1:]...)
+			}
+		case *tocNode:
+			n.prefix = prefix
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// headingIDs generates heading anchors. ASCII follows goldmark's rules so
+// existing anchors keep working; other letters and digits are handled per
+// cfg.UnicodeSlugs, and emoji and other symbols are dropped.
+type headingIDs struct {
+	used map[string]bool
+}
+
+func newHeadingIDs() *headingIDs {
+	return &headingIDs{used: map[string]bool{}}
+}
+
+func (s *headingIDs) Generate(value []byte, kind ast.NodeKind) []byte {
+	var b strings.Builder
+	for _, r := range strings.TrimSpace(string(value)) {
+		switch {
+		case r < utf8.RuneSelf:
+			c := byte(r)
+			if util.IsAlphaNumeric(c) {
+				b.WriteRune(unicode.ToLower(r))
+			} else if util.IsSpace(c) || c == '-' || c == '_' {
+				b.WriteByte('-')
+			}
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			writeUnicodeSlugRune(&b, r)
+		}
+	}
+
+	// Dropped emoji would otherwise leave a dangling hyphen.
+	id := strings.Trim(b.String(), "-")
+	if id == "" {
+		id = "heading"
+		if kind != ast.KindHeading {
+			id = "id"
+		}
+	}
+	if s.used[id] {
+		for i := 1; ; i++ {
+			if next := fmt.Sprintf("%s-%d", id, i); !s.used[next] {
+				id = next
+				break
+			}
+		}
+	}
+	s.used[id] = true
+	return []byte(id)
+}
+
+func (s *headingIDs) Put(value []byte) {
+	s.used[string(value)] = true
+}
+
+// writeUnicodeSlugRune writes a non-ASCII letter or digit to a slug as
+// configured by cfg.UnicodeSlugs.
+func writeUnicodeSlugRune(b *strings.Builder, r rune) {
+	switch cfg.UnicodeSlugs {
+	case "strip":
+	case "percent":
+		b.WriteString(url.PathEscape(string(unicode.ToLower(r))))
+	default:
+		b.WriteRune(unicode.ToLower(r))
+	}
+}
+
+// Section is a top-level section of a post: a heading of the highest level
+// the post uses and everything up to the next such heading.
+type Section struct {
+	ID    string
+	Title string
+	Words int
+}
+
+func (s Section) ReadingTimeString() string {
+	return readingTime(s.Words, cfg.ReadingTime)
+}
+
+// collectSections returns the post's top-level sections and sets each
+// heading's data-words attribute to the section's word count, so a client
+// script can track reading progress.
+func collectSections(doc ast.Node, source []byte) []Section {
+	level := 0
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		if h, ok := n.(*ast.Heading); ok && (level == 0 || h.Level < level) {
+			level = h.Level
+		}
+	}
+
+	var sections []Section
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		h, ok := n.(*ast.Heading)
+		if !ok || h.Level != level {
+			continue
+		}
+		words := 0
+		for c := n.NextSibling(); c != nil; c = c.NextSibling() {
+			if next, ok := c.(*ast.Heading); ok && next.Level <= level {
+				break
+			}
+			words += countWords(c, source)
+		}
+		id, _ := h.AttributeString("id")
+		idBytes, _ := id.([]byte)
+		h.SetAttributeString("data-words", []byte(strconv.Itoa(words)))
+		sections = append(sections, Section{ID: string(idBytes), Title: nodeText(h, source), Words: words})
+	}
+	return sections
+}
+
+// externalLinks opens links to other hosts in a new tab when
+// cfg.ExternalLinks.NewTab is set. Relative, anchor and same-site links are
+// left alone.
+type externalLinks struct{}
+
+func (externalLinks) Transform(doc *ast.Document, reader text.Reader, _ parser.Context) {
+	if !cfg.ExternalLinks.NewTab {
+		return
+	}
+
+	source := reader.Source()
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		var dest []byte
+		switch n := n.(type) {
+		case *ast.Link:
+			dest = n.Destination
+		case *ast.AutoLink:
+			if n.AutoLinkType != ast.AutoLinkURL {
+				return ast.WalkContinue, nil
+			}
+			dest = n.URL(source)
+		default:
+			return ast.WalkContinue, nil
+		}
+		if isExternalURL(string(dest)) {
+			n.SetAttributeString("target", []byte("_blank"))
+			n.SetAttributeString("rel", []byte(cfg.ExternalLinks.Rel))
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// leadParagraph marks the first paragraph of a post with class="lead" when
+// cfg.LeadParagraph or the post's lead front matter says so. Posts that
+// start with anything but a paragraph of text, like a heading or an image,
+// get none.
+type leadParagraph struct{}
+
+func (leadParagraph) Transform(doc *ast.Document, _ text.Reader, pc parser.Context) {
+	enabled := cfg.LeadParagraph
+	if d := frontmatter.Get(pc); d != nil {
+		var meta struct {
+			Lead *bool `yaml:"lead"`
+		}
+		if err := d.Decode(&meta); err == nil && meta.Lead != nil {
+			enabled = *meta.Lead
+		}
+	}
+	if !enabled {
+		return
+	}
+
+	p, ok := doc.FirstChild().(*ast.Paragraph)
+	if !ok {
+		return
+	}
+	switch p.FirstChild().(type) {
+	case *ast.Image, *pictureNode:
+		return
+	}
+	p.SetAttributeString("class", []byte("lead"))
+}
+
+// isExternalURL reports whether dest is an http(s) URL on a host other
+// than those of siteURL and alternateSiteURL.
+func isExternalURL(dest string) bool {
+	u, err := url.Parse(dest)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if u.Scheme != "" && u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	for _, site := range []string{cfg.SiteURL, cfg.AlternateSiteURL} {
+		if s, err := url.Parse(site); err == nil && site != "" && strings.EqualFold(s.Host, u.Host) {
+			return false
+		}
+	}
+	return true
+}
+
+// frontMatterEnd returns the offset just past the YAML (---) or TOML (+++)
+// front matter at the start of content, or 0 if there is none or it is
+// never closed.
+func frontMatterEnd(content []byte) int {
+	_, end := scanFrontMatter(content)
+	return end
+}
+
+// scanFrontMatter returns the delimiter opening the front matter of
+// content, nil if it has none, and the offset just past its closing
+// delimiter, 0 if it has none. Delimiters may have trailing whitespace and
+// CRLF line endings. JSON front matter is not supported.
+func scanFrontMatter(content []byte) (delim []byte, end int) {
+	first, rest, ok := bytes.Cut(content, []byte("\n"))
+	if !ok {
+		return nil, 0
+	}
+	delim = bytes.TrimRight(first, " \t\r")
+	if !bytes.Equal(delim, []byte("---")) && !bytes.Equal(delim, []byte("+++")) {
+		return nil, 0
+	}
+
+	offset := len(first) + 1
+	for len(rest) > 0 {
+		line, next, _ := bytes.Cut(rest, []byte("\n"))
+		consumed := len(rest) - len(next)
+		if bytes.Equal(bytes.TrimRight(line, " \t\r"), delim) {
+			return delim, offset + consumed
+		}
+		offset += consumed
+		rest = next
+	}
+	return delim, 0
+}
+
+// checkFrontMatterLeak makes sure no part of the front matter was parsed
+// as post content, which would publish it. Front matter that is never
+// closed, which the parser takes to run to the end of the post, and JSON
+// front matter, which it does not recognize, are errors too.
+func checkFrontMatterLeak(doc ast.Node, content []byte) error {
+	delim, end := scanFrontMatter(content)
+	if delim == nil {
+		if start := bytes.TrimLeft(content, " \t\r\n"); bytes.HasPrefix(start, []byte("{")) && !bytes.HasPrefix(start, []byte("{{")) {
+			return errors.New("JSON front matter is not supported, use YAML (---) or TOML (+++)")
+		}
+		return nil
+	}
+	if end == 0 {
+		return fmt.Errorf("front matter opened with %s is never closed", delim)
+	}
+	return ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		start := -1
+		if t, ok := n.(*ast.Text); ok {
+			start = t.Segment.Start
+		} else if n.Type() == ast.TypeBlock && n.Lines().Len() > 0 {
+			start = n.Lines().At(0).Start
+		}
+		if start >= 0 && start < end {
+			return ast.WalkStop, fmt.Errorf("front matter leaked into the rendered post at byte %d", start)
+		}
+		return ast.WalkContinue, nil
+	})
+}
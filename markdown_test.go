@@ -0,0 +1,156 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+)
+
+func TestFrontMatterEnd(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+	}{
+		{"yaml", "---\ntitle: a\n---\nbody\n", 17},
+		{"toml", "+++\ntitle = 'a'\n+++\nbody\n", 20},
+		{"crlf", "---\r\ntitle: a\r\n---\r\nbody\r\n", 20},
+		{"trailing whitespace", "--- \ntitle: a\n---\t\nbody\n", 19},
+		{"closed at end of file", "---\ntitle: a\n---", 16},
+		{"unclosed", "---\ntitle: a\nbody\n", 0},
+		{"mismatched delimiters", "---\ntitle: a\n+++\nbody\n", 0},
+		{"none", "body\n---\n", 0},
+		{"json", "{\"title\": \"a\"}\nbody\n", 0},
+		{"empty", "", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := frontMatterEnd([]byte(tt.content)); got != tt.want {
+				t.Errorf("frontMatterEnd(%q) = %d, want %d", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckFrontMatterLeak(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{"yaml", "---\ntitle: a\n---\nbody\n", false},
+		{"toml", "+++\ntitle = 'a'\n+++\nbody\n", false},
+		{"crlf", "---\r\ntitle: a\r\n---\r\nbody\r\n", false},
+		{"trailing whitespace on closing delimiter", "---\ntitle: a\n--- \nbody\n", false},
+		// The parser only opens front matter on a bare delimiter, so the
+		// rest is rendered.
+		{"trailing whitespace on opening delimiter", "--- \ntitle: a\n---\nbody\n", true},
+		{"unclosed", "---\ntitle: a\nbody\n", true},
+		{"json", "{\"title\": \"a\"}\nbody\n", true},
+		{"shortcode first", "{{< term \"a\" \"b\" >}}\n", false},
+		{"none", "body\n", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := []byte(tt.content)
+			doc := md.Parser().Parse(text.NewReader(content), parser.WithContext(parser.NewContext()))
+			err := checkFrontMatterLeak(doc, content)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkFrontMatterLeak(%q) = %v, want error: %v", tt.content, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHeadingIDs(t *testing.T) {
+	tests := []struct {
+		unicodeSlugs string
+		headings     []string
+		want         []string
+	}{
+		{"keep", []string{"Hello World", "Hello World", "Hello World"}, []string{"hello-world", "hello-world-1", "hello-world-2"}},
+		{"keep", []string{"🚀 Launch 🚀", "🎉"}, []string{"launch", "heading"}},
+		{"keep", []string{"日本語", "Go と 日本語"}, []string{"日本語", "go-と-日本語"}},
+		{"strip", []string{"日本語", "日本語", "Go と 日本語"}, []string{"heading", "heading-1", "go"}},
+		{"percent", []string{"日本", "Café ☕"}, []string{"%E6%97%A5%E6%9C%AC", "caf%C3%A9"}},
+	}
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	for _, tt := range tests {
+		t.Run(tt.unicodeSlugs, func(t *testing.T) {
+			cfg.UnicodeSlugs = tt.unicodeSlugs
+			ids := newHeadingIDs()
+			for i, h := range tt.headings {
+				if got := string(ids.Generate([]byte(h), ast.KindHeading)); got != tt.want[i] {
+					t.Errorf("Generate(%q) = %q, want %q", h, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestTOCMatchesAnchors checks that every table of contents link points to
+// a heading anchor, for emoji, CJK and repeated headings.
+func TestTOCMatchesAnchors(t *testing.T) {
+	useDefaultConfig(t)
+	for _, mode := range []string{"keep", "strip", "percent"} {
+		t.Run(mode, func(t *testing.T) {
+			cfg.UnicodeSlugs = mode
+			content := "---\ntitle: toc\ndate: 2024-01-01\n---\n\n[[TOC]]\n\n## 🚀 Launch\n\n## 日本語\n\n## 日本語\n\n## Go と 日本語\n"
+			post, err := parsePost(cfg.ContentTypes[0], "toc.md", []byte(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			html := string(post.Content)
+			links := regexp.MustCompile(`href="#([^"]*)"`).FindAllStringSubmatch(html, -1)
+			if len(links) != 4 {
+				t.Fatalf("table of contents has %d links, want 4:\n%s", len(links), html)
+			}
+			for _, l := range links {
+				if !strings.Contains(html, `id="`+l[1]+`"`) {
+					t.Errorf("link to #%s has no matching anchor:\n%s", l[1], html)
+				}
+			}
+		})
+	}
+}
+
+func TestExternalLinks(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		newTab bool
+		want   bool
+	}{
+		{"external", "[a](https://example.com/page)", true, true},
+		{"external autolink", "<https://example.com/page>", true, true},
+		{"internal absolute", "[a](/page.html)", true, false},
+		{"internal relative", "[a](page.html)", true, false},
+		{"internal full URL", "[a](" + defaultSiteURL + "/page.html)", true, false},
+		{"anchor", "[a](#part)", true, false},
+		{"mailto", "<mailto:me@example.com>", true, false},
+		{"disabled", "[a](https://example.com/page)", false, false},
+	}
+	useDefaultConfig(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.ExternalLinks.NewTab = tt.newTab
+			post, err := parsePost(cfg.ContentTypes[0], "x.md", []byte("---\ntitle: x\ndate: 2024-01-01\n---\n\n"+tt.body+"\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			html := string(post.Content)
+			got := strings.Contains(html, ` target="_blank" rel="noopener noreferrer"`)
+			if got != tt.want {
+				t.Errorf("opens in a new tab: %v, want %v:\n%s", got, tt.want, html)
+			}
+			if !tt.want && strings.Contains(html, "target=") {
+				t.Errorf("link has a target:\n%s", html)
+			}
+		})
+	}
+}
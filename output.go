@@ -0,0 +1,118 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// createOutput creates or truncates a generated file. Without a configured
+// file mode it behaves like os.Create. Writes to a protected path that
+// already exists are discarded; see protectedOutput.
+func createOutput(path string) (io.WriteCloser, error) {
+	if protectedOutput(path) {
+		return nopWriteCloser{io.Discard}, nil
+	}
+	recordOutput(path)
+	mode := os.FileMode(cfg.Permissions.File)
+	if mode == 0 {
+		return os.Create(path)
+	}
+
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return nil, err
+	}
+	// Chmod as well, so neither the umask nor a previous build's file can
+	// leave different permissions behind.
+	if err := f.Chmod(mode); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// writeOutput writes a file copied into the output, 0o644 by default.
+func writeOutput(path string, content []byte) error {
+	if protectedOutput(path) {
+		return nil
+	}
+	recordOutput(path)
+	mode := os.FileMode(cfg.Permissions.File)
+	if mode == 0 {
+		return os.WriteFile(path, content, 0o644)
+	}
+	if err := os.WriteFile(path, content, mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
+
+// protectedOutput reports, with a warning, whether writing path would
+// overwrite a file protected by cfg.ProtectedPaths: one written earlier in
+// this build, or one in public that the previous build did not write, like
+// a platform file put there by hand. Without a previous build state every
+// existing file counts.
+func protectedOutput(file string) bool {
+	rel, err := filepath.Rel("public", file)
+	if err != nil || !isProtectedPath(filepath.ToSlash(rel)) {
+		return false
+	}
+	slashed := filepath.ToSlash(file)
+	outputsMu.Lock()
+	written := outputs[slashed]
+	outputsMu.Unlock()
+	if !written {
+		if _, err := os.Stat(file); err != nil {
+			return false
+		}
+		if prevState != nil && slices.Contains(prevState.Outputs, slashed) {
+			return false
+		}
+	}
+	warnf("not overwriting protected %s", file)
+	return true
+}
+
+// isProtectedPath reports whether the site-relative path matches one of
+// cfg.ProtectedPaths, each a path or a path.Match pattern.
+func isProtectedPath(rel string) bool {
+	for _, pattern := range cfg.ProtectedPaths {
+		pattern = strings.TrimPrefix(pattern, "/")
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+type nopWriteCloser struct{ io.Writer }
+
+func (nopWriteCloser) Close() error { return nil }
+
+// chmodOutput applies the configured file mode to a file written by an
+// external tool. Callers must check protectedOutput before running the
+// tool.
+func chmodOutput(path string) error {
+	recordOutput(path)
+	mode := os.FileMode(cfg.Permissions.File)
+	if mode == 0 {
+		return nil
+	}
+	return os.Chmod(path, mode)
+}
+
+// mkdirOutput creates an output directory and its parents, 0o755 by default.
+func mkdirOutput(path string) error {
+	mode := os.FileMode(cfg.Permissions.Dir)
+	if mode == 0 {
+		return os.MkdirAll(path, 0o755)
+	}
+	if err := os.MkdirAll(path, mode); err != nil {
+		return err
+	}
+	return os.Chmod(path, mode)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+)
+
+// qrRecoveryLevels maps the qrCodes.recovery names to error-correction
+// levels, lowest first.
+var qrRecoveryLevels = map[string]qrcode.RecoveryLevel{
+	"low":     qrcode.Low,
+	"medium":  qrcode.Medium,
+	"high":    qrcode.High,
+	"highest": qrcode.Highest,
+}
+
+// generateQRCodes writes a QR code of every post's canonical URL to
+// public/<slug>/qr.png and sets the post's QRCode, when cfg.QRCodes.Enabled
+// is set.
+func generateQRCodes(ctx context.Context, posts []Post) error {
+	if !cfg.QRCodes.Enabled {
+		return nil
+	}
+	return parallel(ctx, len(posts), func(_ context.Context, i int) error {
+		post := posts[i]
+		code, err := newQRCode(post.CanonicalURL(), qrRecoveryLevels[cfg.QRCodes.Recovery])
+		if err != nil {
+			// qrcode.New only fails on content too long for any version.
+			warnf("%s: no QR code, its %d-byte URL does not fit in one: %v", post.Filename, len(post.CanonicalURL()), err)
+			return nil
+		}
+		// Sizing by module rather than by image keeps every module
+		// scannable: longer URLs need more modules and get a larger
+		// image.
+		png, err := code.PNG(-cfg.QRCodes.ModuleSize)
+		if err != nil {
+			return fmt.Errorf("encode QR code for %s: %w", post.Slug, err)
+		}
+
+		rel := strings.TrimSuffix(post.Path, ".html") + "/qr.png"
+		path := filepath.Join("public", filepath.FromSlash(rel))
+		if err := mkdirOutput(filepath.Dir(path)); err != nil {
+			return fmt.Errorf("create QR code dir for %s: %w", path, err)
+		}
+		if err := writeOutput(path, png); err != nil {
+			return fmt.Errorf("write QR code for %s: %w", post.Slug, err)
+		}
+		posts[i].QRCode = rel
+		return nil
+	})
+}
+
+// newQRCode encodes url at level, lowering the error correction as far as
+// needed for URLs too long to fit at it.
+func newQRCode(url string, level qrcode.RecoveryLevel) (*qrcode.QRCode, error) {
+	for {
+		code, err := qrcode.New(url, level)
+		if err == nil || level == qrcode.Low {
+			return code, err
+		}
+		level--
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// configureRawHTML sets how md renders raw HTML in posts, following
+// cfg.RawHTML. It must run before the first post is rendered.
+func configureRawHTML() {
+	rawHTML(cfg.RawHTML).Extend(md)
+}
+
+// rawHTML is a goldmark extension rendering raw HTML as one of the
+// cfg.RawHTML settings says. "omit" is goldmark's default and adds
+// nothing.
+type rawHTML string
+
+func (mode rawHTML) Extend(m goldmark.Markdown) {
+	switch mode {
+	case "unsafe":
+		m.Renderer().AddOptions(html.WithUnsafe())
+	case "escape", "sanitize":
+		m.Renderer().AddOptions(renderer.WithNodeRenderers(
+			util.Prioritized(rawHTMLRenderer{sanitize: mode == "sanitize"}, 500),
+		))
+	}
+	if mode == "sanitize" {
+		m.Parser().AddOptions(parser.WithASTTransformers(
+			util.Prioritized(activeContentTransformer{}, 500),
+		))
+	}
+}
+
+// rawHTMLRenderer writes raw HTML blocks and inline tags either escaped, so
+// they show as text, or through bluemonday's user-generated content policy,
+// which drops scripts, event handlers and other active content.
+type rawHTMLRenderer struct {
+	sanitize bool
+}
+
+var ugcPolicy = bluemonday.UGCPolicy()
+
+func (r rawHTMLRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(ast.KindHTMLBlock, func(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		n := node.(*ast.HTMLBlock)
+		var raw bytes.Buffer
+		for i := 0; i < n.Lines().Len(); i++ {
+			line := n.Lines().At(i)
+			raw.Write(line.Value(source))
+		}
+		if n.HasClosure() {
+			raw.Write(n.ClosureLine.Value(source))
+		}
+		r.write(w, raw.Bytes())
+		return ast.WalkContinue, nil
+	})
+	reg.Register(ast.KindRawHTML, func(w util.BufWriter, source []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkSkipChildren, nil
+		}
+		r.write(w, rawHTMLSource(node.(*ast.RawHTML), source))
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+func (r rawHTMLRenderer) write(w util.BufWriter, raw []byte) {
+	if r.sanitize {
+		_, _ = w.Write(ugcPolicy.SanitizeBytes(raw))
+	} else {
+		_, _ = w.Write(util.EscapeHTML(raw))
+	}
+}
+
+func rawHTMLSource(n *ast.RawHTML, source []byte) []byte {
+	var raw bytes.Buffer
+	for i := 0; i < n.Segments.Len(); i++ {
+		segment := n.Segments.At(i)
+		raw.Write(segment.Value(source))
+	}
+	return raw.Bytes()
+}
+
+// activeContentElements are the elements whose content bluemonday drops
+// along with their tags.
+var activeContentElements = map[string]bool{
+	"frame": true, "frameset": true, "iframe": true, "noembed": true,
+	"noframes": true, "noscript": true, "nostyle": true, "object": true,
+	"script": true, "style": true, "title": true,
+}
+
+// tagPattern matches the start of an HTML tag, capturing the slash of a
+// closing tag and the element name.
+var tagPattern = regexp.MustCompile(`^<\s*(/?)\s*([a-zA-Z][a-zA-Z0-9-]*)`)
+
+// activeContentTransformer removes everything between an inline opening
+// tag of an activeContentElements element and its closing tag, or the end
+// of the paragraph. Inline tags are sanitized one by one, which would drop
+// the tags of <script>alert(1)</script> but keep its body as text. Blocks
+// of raw HTML are sanitized whole and need no help.
+type activeContentTransformer struct{}
+
+func (activeContentTransformer) Transform(doc *ast.Document, reader text.Reader, _ parser.Context) {
+	source := reader.Source()
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		open := ""
+		for c := n.FirstChild(); c != nil; {
+			next := c.NextSibling()
+			name, closing := inlineTag(c, source)
+			switch {
+			case open != "" && closing && name == open:
+				open = ""
+			case open != "":
+				n.RemoveChild(n, c)
+			case !closing && activeContentElements[name]:
+				open = name
+			}
+			c = next
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// inlineTag returns the lowercased element name of n if it is an inline
+// HTML tag, and whether it is a closing one.
+func inlineTag(n ast.Node, source []byte) (name string, closing bool) {
+	raw, ok := n.(*ast.RawHTML)
+	if !ok {
+		return "", false
+	}
+	m := tagPattern.FindSubmatch(rawHTMLSource(raw, source))
+	if m == nil {
+		return "", false
+	}
+	return strings.ToLower(string(m[2])), len(m[1]) > 0
+}
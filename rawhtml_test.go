@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/yuin/goldmark"
+)
+
+func TestRawHTML(t *testing.T) {
+	const (
+		inline = "Hi <script>alert(1)</script> <b>there</b>.\n"
+		block  = "<script>\nalert(1)\n</script>\n"
+		events = "<img src=\"x.png\" onerror=\"alert(1)\">\n"
+	)
+	tests := []struct {
+		mode, source, want string
+	}{
+		{"omit", inline, "<p>Hi <!-- raw HTML omitted -->alert(1)<!-- raw HTML omitted --> <!-- raw HTML omitted -->there<!-- raw HTML omitted -->.</p>\n"},
+		{"omit", block, "<!-- raw HTML omitted -->\n<!-- raw HTML omitted -->\n"},
+		{"unsafe", inline, "<p>Hi <script>alert(1)</script> <b>there</b>.</p>\n"},
+		{"unsafe", block, "<script>\nalert(1)\n</script>\n"},
+		{"escape", inline, "<p>Hi &lt;script&gt;alert(1)&lt;/script&gt; &lt;b&gt;there&lt;/b&gt;.</p>\n"},
+		{"escape", block, "&lt;script&gt;\nalert(1)\n&lt;/script&gt;\n"},
+		{"sanitize", inline, "<p>Hi  <b>there</b>.</p>\n"},
+		{"sanitize", block, "\n"},
+		{"sanitize", events, "<img src=\"x.png\">\n"},
+		{"sanitize", "Hi <SCRIPT>alert(1)\n", "<p>Hi </p>\n"},
+		{"sanitize", "*<style>p{}</style>styled*\n", "<p><em>styled</em></p>\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := goldmark.New(goldmark.WithExtensions(rawHTML(tt.mode))).Convert([]byte(tt.source), &buf); err != nil {
+				t.Fatal(err)
+			}
+			if got := buf.String(); got != tt.want {
+				t.Errorf("%s: %q renders as %q, want %q", tt.mode, tt.source, got, tt.want)
+			}
+		})
+	}
+}
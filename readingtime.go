@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+func readingTime(words int, c readingTimeConfig) string {
+	wpm := c.WordsPerMinute
+	if wpm == 0 {
+		wpm = 200
+	}
+	minimum := c.Minimum
+	if minimum == 0 {
+		minimum = 1
+	}
+
+	if c.LessThanMinuteLabel != "" && words < wpm {
+		return c.LessThanMinuteLabel
+	}
+
+	exact := float64(words) / float64(wpm)
+	var minutes int
+	if c.Rounding == "round" {
+		minutes = int(math.Round(exact))
+	} else {
+		minutes = int(math.Ceil(exact))
+	}
+	minutes = max(minutes, minimum)
+
+	return fmt.Sprintf("%d min read", minutes)
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestReadingTime(t *testing.T) {
+	label := readingTimeConfig{LessThanMinuteLabel: "< 1 min read"}
+	tests := []struct {
+		name  string
+		words int
+		c     readingTimeConfig
+		want  string
+	}{
+		{"0 words", 0, readingTimeConfig{}, "1 min read"},
+		{"1 word", 1, readingTimeConfig{}, "1 min read"},
+		{"exactly a minute", 200, readingTimeConfig{}, "1 min read"},
+		{"just over a minute", 201, readingTimeConfig{}, "2 min read"},
+		{"rounded down", 299, readingTimeConfig{Rounding: "round"}, "1 min read"},
+		{"rounded half up", 300, readingTimeConfig{Rounding: "round"}, "2 min read"},
+		{"rounded to 0 hits minimum", 50, readingTimeConfig{Rounding: "round"}, "1 min read"},
+		{"minimum", 200, readingTimeConfig{Minimum: 3}, "3 min read"},
+		{"past minimum", 1000, readingTimeConfig{Minimum: 3}, "5 min read"},
+		{"words per minute", 250, readingTimeConfig{WordsPerMinute: 100}, "3 min read"},
+		{"label at 0 words", 0, label, "< 1 min read"},
+		{"label under a minute", 199, label, "< 1 min read"},
+		{"label at exactly a minute", 200, label, "1 min read"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := readingTime(tt.words, tt.c); got != tt.want {
+				t.Errorf("readingTime(%d, %+v) = %q, want %q", tt.words, tt.c, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type RedirectData struct {
+	URL string
+}
+
+// resolveRedirects maps page paths that no longer exist to the pages that
+// replace them. It keeps the previous build's redirects, adds the
+// configured ones, and detects renamed posts by comparing against the
+// previous build: a vanished page whose source content, or else title and
+// date, match exactly one current post is redirected to it. Vanished pages
+// without such a successor are reported, and returned to be reported again
+// by every later build until they get a redirect, come back or are listed
+// in cfg.Removed. A partial build, which cannot tell vanished pages from
+// ones it left out, skips the detection.
+func resolveRedirects(posts []Post, prev *buildState, partial bool) (redirects map[string]string, vanished []string) {
+	current := map[string]bool{}
+	for _, post := range posts {
+		current[post.Path] = true
+	}
+
+	redirects = map[string]string{}
+	if prev != nil {
+		for from, to := range prev.Redirects {
+			redirects[from] = to
+		}
+	}
+	for from, to := range cfg.Redirects {
+		redirects[strings.TrimPrefix(from, "/")] = strings.TrimPrefix(to, "/")
+	}
+	removed := map[string]bool{}
+	for _, p := range cfg.Removed {
+		removed[strings.TrimPrefix(p, "/")] = true
+	}
+
+	if prev != nil && partial {
+		vanished = prev.Vanished
+	}
+	if prev != nil && !partial {
+		gone := func(p string) bool { return !current[p] && redirects[p] == "" && !removed[p] }
+		unmapped := map[string]bool{}
+		for _, p := range prev.Vanished {
+			if gone(p) {
+				unmapped[p] = true
+			}
+		}
+
+		sources := make([]string, 0, len(prev.Posts))
+		for src := range prev.Posts {
+			sources = append(sources, src)
+		}
+		sort.Strings(sources)
+
+		for _, src := range sources {
+			old := prev.Posts[src]
+			if !gone(old.Path) {
+				continue
+			}
+			successors := renamedPosts(posts, old)
+			if len(successors) != 1 {
+				unmapped[old.Path] = true
+				continue
+			}
+			redirects[old.Path] = successors[0].Path
+		}
+
+		for p := range unmapped {
+			vanished = append(vanished, p)
+		}
+		sort.Strings(vanished)
+		for _, p := range vanished {
+			warnf("/%s no longer exists; add it to redirects in %s to keep its links working, or to removed if it is gone for good", p, configPath)
+		}
+	}
+
+	for from, to := range redirects {
+		if current[from] {
+			delete(redirects, from)
+			continue
+		}
+		// Follow chains left by renaming a post more than once.
+		for i := 0; !current[to] && redirects[to] != "" && i < len(redirects); i++ {
+			to = redirects[to]
+		}
+		if !current[to] {
+			warnf("redirect from /%s points to /%s, which does not exist", from, to)
+			delete(redirects, from)
+			continue
+		}
+		redirects[from] = to
+	}
+	return redirects, vanished
+}
+
+// renamedPosts returns the current posts that old was likely renamed to.
+func renamedPosts(posts []Post, old postState) []Post {
+	var byContent, byMeta []Post
+	for _, post := range posts {
+		hash, err := hashFile(filepath.Join(post.Type.Dir, post.Filename))
+		if err == nil && hash == old.Hash {
+			byContent = append(byContent, post)
+		}
+		if post.Title == old.Title && post.DateRFC3339() == old.Date {
+			byMeta = append(byMeta, post)
+		}
+	}
+	if len(byContent) > 0 {
+		return byContent
+	}
+	return byMeta
+}
+
+// generateRedirects writes a meta refresh page at every redirected path.
+func generateRedirects(redirects map[string]string) error {
+	for from, to := range redirects {
+		path := filepath.Join("public", filepath.FromSlash(from))
+		if err := mkdirOutput(filepath.Dir(path)); err != nil {
+			return fmt.Errorf("create redirect dir for %s: %w", path, err)
+		}
+		f, err := createOutput(path)
+		if err != nil {
+			return fmt.Errorf("create redirect %s: %w", path, err)
+		}
+		if err := redirectTmpl.Execute(f, RedirectData{URL: cfg.SiteURL + "/" + to}); err != nil {
+			f.Close()
+			return fmt.Errorf("render redirect %s: %w", path, err)
+		}
+		f.Close()
+	}
+	return nil
+}
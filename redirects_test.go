@@ -0,0 +1,57 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestResolveRedirectsVanished(t *testing.T) {
+	current := []Post{{Title: "kept", Path: "kept.html", Date: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}}
+	prev := &buildState{
+		Posts: map[string]postState{
+			"posts/kept.md": {Path: "kept.html", Title: "kept"},
+			"posts/gone.md": {Path: "gone.html", Title: "gone"},
+		},
+		Vanished: []string{"older.html"},
+	}
+	tests := []struct {
+		name      string
+		redirects map[string]string
+		removed   []string
+		partial   bool
+		want      []string
+		warnings  int
+	}{
+		{"reported again", nil, nil, false, []string{"gone.html", "older.html"}, 2},
+		{"redirected", map[string]string{"older.html": "kept.html"}, nil, false, []string{"gone.html"}, 1},
+		{"removed", nil, []string{"/gone.html", "older.html"}, false, nil, 0},
+		{"partial build", nil, nil, true, []string{"older.html"}, 0},
+	}
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.Redirects, cfg.Removed = tt.redirects, tt.removed
+			before := warnings
+			_, vanished := resolveRedirects(current, prev, tt.partial)
+			if !slices.Equal(vanished, tt.want) {
+				t.Errorf("vanished = %v, want %v", vanished, tt.want)
+			}
+			if got := warnings - before; got != tt.warnings {
+				t.Errorf("reported %d, want %d", got, tt.warnings)
+			}
+		})
+	}
+}
+
+func TestResolveRedirectsComeBack(t *testing.T) {
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	cfg.Redirects, cfg.Removed = nil, nil
+	prev := &buildState{Vanished: []string{"back.html"}}
+	_, vanished := resolveRedirects([]Post{{Path: "back.html"}}, prev, false)
+	if len(vanished) != 0 {
+		t.Errorf("vanished = %v, want none", vanished)
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+// linkRelated sets the related posts of every post. Posts naming theirs in
+// front matter get those, in the order given, by path without ".html", like
+// "notes/setup", or by slug where only one post has it. The
+// rest get up to cfg.RelatedPosts posts listed on the index sharing the
+// most taxonomy terms with them, newest first among equals. Related posts
+// that limit left out of the build are dropped without a warning.
+func linkRelated(posts []Post, limit *postLimit) {
+	listed := publishablePosts(posts)
+	candidates := postsWhere(listed, func(p Post) bool { return p.Type.Index && p.InIndex })
+	byPath := map[string]Post{}
+	bySlug := map[string][]Post{}
+	for _, p := range listed {
+		byPath[strings.TrimSuffix(p.Path, ".html")] = p
+		bySlug[p.Slug] = append(bySlug[p.Slug], p)
+	}
+
+	related := make([][]Post, len(posts))
+	for i, post := range posts {
+		if post.RelatedSlugs != nil {
+			for _, slug := range post.RelatedSlugs {
+				matches := bySlug[slug]
+				if p, ok := byPath[slug]; ok {
+					matches = []Post{p}
+				}
+				switch {
+				case len(matches) == 1:
+					related[i] = append(related[i], matches[0])
+				case len(matches) > 1:
+					paths := make([]string, len(matches))
+					for j, m := range matches {
+						paths[j] = strings.TrimSuffix(m.Path, ".html")
+					}
+					warnf("%s: related post %q is ambiguous; name one of %s", post.Filename, slug, strings.Join(paths, ", "))
+				case limit == nil || !limit.omitted[slug]:
+					warnf("%s: related post %q does not exist", post.Filename, slug)
+				}
+			}
+			continue
+		}
+		related[i] = relatedByTerms(post, candidates, cfg.RelatedPosts)
+	}
+	for i := range posts {
+		posts[i].Related = related[i]
+	}
+}
+
+// relatedByTerms returns up to limit candidates sharing at least one term
+// with post, most shared terms first. Candidates are sorted newest first,
+// which the stable sort keeps among equal scores.
+func relatedByTerms(post Post, candidates []Post, limit int) []Post {
+	if limit == 0 {
+		return nil
+	}
+
+	type scored struct {
+		post  Post
+		score int
+	}
+	var matches []scored
+	for _, c := range candidates {
+		if c.Path == post.Path {
+			continue
+		}
+		if score := sharedTerms(post, c); score > 0 {
+			matches = append(matches, scored{c, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+
+	var related []Post
+	for _, m := range matches {
+		if len(related) == limit {
+			break
+		}
+		related = append(related, m.post)
+	}
+	return related
+}
+
+// sharedTerms counts the terms a and b share across all taxonomies,
+// comparing slugs as term pages do, so "Go" and "go" match.
+func sharedTerms(a, b Post) int {
+	n := 0
+	for name, terms := range a.Taxonomies {
+		other := map[string]bool{}
+		for _, t := range b.Taxonomies[name] {
+			other[slugify(t)] = true
+		}
+		for _, t := range terms {
+			if other[slugify(t)] {
+				n++
+			}
+		}
+	}
+	return n
+}
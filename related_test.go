@@ -0,0 +1,74 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestLinkRelated(t *testing.T) {
+	postsType := contentType{Name: "posts", Index: true}
+	notesType := contentType{Name: "notes", URLPrefix: "notes/", Index: true}
+	guidesType := contentType{Name: "guides", URLPrefix: "guides/", Index: true}
+	post := func(ct contentType, slug string, day int, tags ...string) Post {
+		return Post{
+			Title:      slug,
+			Slug:       slug,
+			Filename:   slug + ".md",
+			Path:       ct.URLPrefix + slug + ".html",
+			Date:       time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC),
+			Type:       ct,
+			InIndex:    true,
+			Taxonomies: map[string][]string{"tags": tags},
+		}
+	}
+
+	tests := []struct {
+		name     string
+		related  []string
+		want     []string
+		warnings int
+	}{
+		{"by slug", []string{"intro"}, []string{"notes/intro.html"}, 0},
+		{"root path wins", []string{"setup"}, []string{"setup.html"}, 0},
+		{"by path", []string{"notes/setup"}, []string{"notes/setup.html"}, 0},
+		{"ambiguous", []string{"faq"}, nil, 1},
+		{"ambiguous by path", []string{"guides/faq"}, []string{"guides/faq.html"}, 0},
+		{"missing", []string{"nope"}, nil, 1},
+		{"by terms", nil, []string{"notes/intro.html", "setup.html"}, 0},
+	}
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	cfg.RelatedPosts = 3
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Newest first, as build sorts them.
+			subject := post(postsType, "subject", 9, "Go", "Testing")
+			subject.RelatedSlugs = tt.related
+			hidden := post(postsType, "hidden", 8, "go", "testing")
+			hidden.InIndex = false
+			posts := []Post{
+				subject,
+				hidden,
+				post(notesType, "intro", 7, "go", "testing"),
+				post(postsType, "setup", 6, "GO"),
+				post(notesType, "setup", 5),
+				post(notesType, "faq", 4),
+				post(guidesType, "faq", 3),
+				post(postsType, "unrelated", 2, "rust"),
+			}
+			before := warnings
+			linkRelated(posts, nil)
+			var got []string
+			for _, p := range posts[0].Related {
+				got = append(got, p.Path)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("related = %v, want %v", got, tt.want)
+			}
+			if n := warnings - before; n != tt.warnings {
+				t.Errorf("reported %d, want %d", n, tt.warnings)
+			}
+		})
+	}
+}
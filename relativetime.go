@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// relativeTime describes t relative to now in the largest whole unit, such
+// as "5 minutes ago", "in 2 days" or "1 year ago".
+func relativeTime(t, now time.Time) string {
+	d := now.Sub(t)
+	future := d < 0
+	if future {
+		d = -d
+	}
+
+	var n int
+	var unit string
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		n, unit = int(d/time.Minute), "minute"
+	case d < 24*time.Hour:
+		n, unit = int(d/time.Hour), "hour"
+	case d < 30*24*time.Hour:
+		n, unit = int(d/(24*time.Hour)), "day"
+	case d < 12*30*24*time.Hour:
+		n, unit = int(d/(30*24*time.Hour)), "month"
+	default:
+		// Twelve 30-day months fall short of a 365-day year, so the days
+		// in between count as a year rather than as 12 months.
+		n, unit = max(1, int(d/(365*24*time.Hour))), "year"
+	}
+
+	if n != 1 {
+		unit += "s"
+	}
+	if future {
+		return fmt.Sprintf("in %d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %s ago", n, unit)
+}
+
+func formatOptionalTime(t time.Time, layout string) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(layout)
+}
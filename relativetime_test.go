@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2026, 3, 17, 12, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	tests := []struct {
+		ago  time.Duration
+		want string
+	}{
+		{0, "just now"},
+		{59 * time.Second, "just now"},
+		{time.Minute, "1 minute ago"},
+		{59 * time.Minute, "59 minutes ago"},
+		{time.Hour, "1 hour ago"},
+		{23 * time.Hour, "23 hours ago"},
+		{day, "1 day ago"},
+		{29 * day, "29 days ago"},
+		{30 * day, "1 month ago"},
+		{359 * day, "11 months ago"},
+		{360 * day, "1 year ago"},
+		{364 * day, "1 year ago"},
+		{365 * day, "1 year ago"},
+		{729 * day, "1 year ago"},
+		{730 * day, "2 years ago"},
+		{-2 * day, "in 2 days"},
+		{-362 * day, "in 1 year"},
+	}
+	for _, tt := range tests {
+		if got := relativeTime(now.Add(-tt.ago), now); got != tt.want {
+			t.Errorf("relativeTime(now - %v) = %q, want %q", tt.ago, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"sort"
+)
+
+// SeriesGroup is a series in the index: the listed posts sharing a term of
+// the cfg.Series taxonomy, oldest part first.
+type SeriesGroup struct {
+	Name string
+	// URL is the series' term page.
+	URL   string
+	Parts []Post
+}
+
+// Latest returns the newest part of the series.
+func (s SeriesGroup) Latest() Post {
+	return s.Parts[len(s.Parts)-1]
+}
+
+// IndexEntry is one item of the index listing: a post, or with Series set,
+// a series of them.
+type IndexEntry struct {
+	Post
+	Series *SeriesGroup
+}
+
+// seriesTaxonomy returns the taxonomy configured as series.
+func seriesTaxonomy() (taxonomyConfig, bool) {
+	for _, tx := range cfg.Taxonomies {
+		if cfg.Series.Taxonomy != "" && tx.Name == cfg.Series.Taxonomy {
+			return tx, true
+		}
+	}
+	return taxonomyConfig{}, false
+}
+
+// indexEntries shapes the index listing of posts, in their order. Each
+// series takes the place of its first listed part, with its other parts
+// left out unless cfg.Series.ListParts is set. Without series, every post
+// is an entry.
+func indexEntries(posts []Post) []IndexEntry {
+	tx, ok := seriesTaxonomy()
+	if !ok {
+		entries := make([]IndexEntry, len(posts))
+		for i, p := range posts {
+			entries[i] = IndexEntry{Post: p}
+		}
+		return entries
+	}
+
+	groups := map[string]*SeriesGroup{}
+	for _, g := range seriesGroups(posts, tx) {
+		groups[slugify(g.Name)] = g
+	}
+
+	var entries []IndexEntry
+	added := map[string]bool{}
+	for _, p := range posts {
+		terms := p.Taxonomies[tx.Name]
+		for _, term := range terms {
+			slug := slugify(term)
+			if !added[slug] {
+				added[slug] = true
+				entries = append(entries, IndexEntry{Post: p, Series: groups[slug]})
+			}
+		}
+		if len(terms) == 0 || cfg.Series.ListParts {
+			entries = append(entries, IndexEntry{Post: p})
+		}
+	}
+	return entries
+}
+
+// seriesGroups groups the posts by the terms of the series taxonomy tx,
+// in the order each series first appears, with its parts oldest first.
+func seriesGroups(posts []Post, tx taxonomyConfig) []*SeriesGroup {
+	var groups []*SeriesGroup
+	bySlug := map[string]*SeriesGroup{}
+	for _, p := range posts {
+		for _, term := range p.Taxonomies[tx.Name] {
+			slug := slugify(term)
+			g := bySlug[slug]
+			if g == nil {
+				g = &SeriesGroup{Name: term, URL: "/" + tx.URLPrefix + slug + ".html"}
+				bySlug[slug] = g
+				groups = append(groups, g)
+			}
+			g.Parts = append(g.Parts, p)
+		}
+	}
+	for _, g := range groups {
+		sort.SliceStable(g.Parts, func(i, j int) bool {
+			return g.Parts[i].Date.Before(g.Parts[j].Date)
+		})
+	}
+	return groups
+}
+
+// seriesFeedPath returns the path of the series overview feed, next to the
+// series' term pages.
+func seriesFeedPath(tx taxonomyConfig) string {
+	return "/" + tx.URLPrefix + "index.xml"
+}
+
+// seriesPosts returns the posts that are part of a series.
+func seriesPosts(posts []Post) []Post {
+	tx, ok := seriesTaxonomy()
+	if !ok {
+		return nil
+	}
+	return postsWhere(posts, func(p Post) bool { return len(p.Taxonomies[tx.Name]) > 0 })
+}
+
+// generateSeriesFeed writes the series overview feed when any post is
+// part of a series. It has one entry per series, its latest part, so a
+// series resurfaces whenever a part is added. A post that is the latest
+// part of several series appears once.
+func generateSeriesFeed(posts []Post) error {
+	tx, ok := seriesTaxonomy()
+	if !ok {
+		return nil
+	}
+	var latest []Post
+	seen := map[string]bool{}
+	for _, g := range seriesGroups(posts, tx) {
+		if p := g.Latest(); !seen[p.Path] {
+			seen[p.Path] = true
+			latest = append(latest, p)
+		}
+	}
+	if len(latest) == 0 {
+		return nil
+	}
+	return writeFeed(feedFile{
+		Path:  seriesFeedPath(tx),
+		Title: siteTitle + " – " + tx.Name,
+		Posts: limitPosts(feedOrder(latest), cfg.Feeds.RecentLimit),
+	})
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"os"
+	"slices"
+	"testing"
+	"time"
+)
+
+// seriesPost returns a post of the given day of 2024 in the series named.
+func seriesPost(title string, day int, series ...string) Post {
+	return Post{
+		Title:      title,
+		Path:       title + ".html",
+		Date:       time.Date(2024, 1, day, 0, 0, 0, 0, time.UTC),
+		Taxonomies: map[string][]string{"series": series},
+	}
+}
+
+func TestIndexEntries(t *testing.T) {
+	// In listing order, newest first.
+	posts := []Post{
+		seriesPost("go-3", 4, "Go"),
+		seriesPost("note", 3),
+		seriesPost("rust-1", 2, "Rust"),
+		seriesPost("go-1", 1, "Go"),
+	}
+	tests := []struct {
+		name      string
+		taxonomy  string
+		listParts bool
+		want      []string
+	}{
+		{"no series", "", false, []string{"go-3", "note", "rust-1", "go-1"}},
+		{"series", "series", false, []string{"series Go", "note", "series Rust"}},
+		{"series and parts", "series", true, []string{"series Go", "go-3", "note", "series Rust", "rust-1", "go-1"}},
+	}
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	cfg.Taxonomies = []taxonomyConfig{{Name: "series", URLPrefix: "series/"}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.Series = seriesConfig{Taxonomy: tt.taxonomy, ListParts: tt.listParts}
+			var got []string
+			for _, e := range indexEntries(posts) {
+				if e.Series != nil {
+					got = append(got, "series "+e.Series.Name)
+				} else {
+					got = append(got, e.Title)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("indexEntries = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeriesGroups(t *testing.T) {
+	posts := []Post{
+		seriesPost("both-2", 4, "Go", "Tooling"),
+		seriesPost("go-2", 3, "Go"),
+		seriesPost("tooling-1", 2, "tooling"),
+		seriesPost("go-1", 1, "Go"),
+	}
+	want := []struct {
+		name, url string
+		parts     []string
+		latest    string
+	}{
+		{"Go", "/series/go.html", []string{"go-1", "go-2", "both-2"}, "both-2"},
+		{"Tooling", "/series/tooling.html", []string{"tooling-1", "both-2"}, "both-2"},
+	}
+	groups := seriesGroups(posts, taxonomyConfig{Name: "series", URLPrefix: "series/"})
+	if len(groups) != len(want) {
+		t.Fatalf("seriesGroups returned %d groups, want %d", len(groups), len(want))
+	}
+	for i, g := range groups {
+		var parts []string
+		for _, p := range g.Parts {
+			parts = append(parts, p.Title)
+		}
+		w := want[i]
+		if g.Name != w.name || g.URL != w.url || !slices.Equal(parts, w.parts) || g.Latest().Title != w.latest {
+			t.Errorf("group %d = %s %s %v latest %s, want %s %s %v latest %s",
+				i, g.Name, g.URL, parts, g.Latest().Title, w.name, w.url, w.parts, w.latest)
+		}
+	}
+}
+
+func TestSeriesFeed(t *testing.T) {
+	buildSite(t, "taxonomies:\n  - name: series\nseries:\n  taxonomy: series\n", map[string]string{
+		"go-1":   "date: 2024-01-01\nseries: Go",
+		"rust-1": "date: 2024-01-02\nseries: Rust",
+		"go-2":   "date: 2024-01-03\nseries: Go",
+		"note":   "date: 2024-01-04",
+	})
+	content, err := os.ReadFile("public/series/index.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"go-2", "rust-1"}
+	if got := listedSlugs(string(content), "go-1", "rust-1", "go-2", "note"); !slices.Equal(got, want) {
+		t.Errorf("series feed lists %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+// shortcode renders one {{< name args >}} occurrence in a post.
+type shortcode struct {
+	// args is the number of positional arguments the shortcode takes.
+	args   int
+	render func(w util.BufWriter, n *shortcodeNode)
+}
+
+var shortcodes = map[string]shortcode{
+	"term": {args: 2, render: renderTerm},
+	// toc is replaced with the table of contents by placeTOC when it is on
+	// a line of its own, and renders nothing anywhere else.
+	"toc": {args: 0, render: func(util.BufWriter, *shortcodeNode) {}},
+}
+
+// pairedShortcode wraps block content between {{< name args >}} and
+// {{< /name >}}, each written on a line of its own. Paired shortcodes nest.
+type pairedShortcode struct {
+	args  int
+	open  func(w util.BufWriter, args []string)
+	close func(w util.BufWriter)
+}
+
+var pairedShortcodes = map[string]pairedShortcode{
+	"spoiler": {args: 1, open: openSpoiler, close: closeSpoiler},
+}
+
+func openSpoiler(w util.BufWriter, args []string) {
+	fmt.Fprintf(w, "<details class=\"spoiler\">\n<summary>%s</summary>\n", util.EscapeHTML([]byte(args[0])))
+}
+
+func closeSpoiler(w util.BufWriter) {
+	_, _ = w.WriteString("</details>\n")
+}
+
+var kindShortcode = ast.NewNodeKind("Shortcode")
+
+type shortcodeNode struct {
+	ast.BaseInline
+	name string
+	args []string
+	raw  []byte
+}
+
+func (n *shortcodeNode) Kind() ast.NodeKind { return kindShortcode }
+
+func (n *shortcodeNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Name": n.name}, nil)
+}
+
+var kindPairedShortcode = ast.NewNodeKind("PairedShortcode")
+
+type pairedShortcodeNode struct {
+	ast.BaseBlock
+	name   string
+	args   []string
+	closed bool
+}
+
+func (n *pairedShortcodeNode) Kind() ast.NodeKind { return kindPairedShortcode }
+
+func (n *pairedShortcodeNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Name": n.name}, nil)
+}
+
+// parseShortcodeLine parses a line holding nothing but one shortcode.
+func parseShortcodeLine(line []byte) (name string, args []string, ok bool) {
+	line = bytes.TrimSpace(line)
+	if !bytes.HasPrefix(line, []byte("{{<")) || !bytes.HasSuffix(line, []byte(">}}")) {
+		return "", nil, false
+	}
+	fields, err := splitShortcodeArgs(string(line[3 : len(line)-3]))
+	if err != nil || len(fields) == 0 {
+		return "", nil, false
+	}
+	return fields[0], fields[1:], true
+}
+
+// pairedShortcodeParser parses paired shortcodes into container blocks.
+type pairedShortcodeParser struct{}
+
+func (pairedShortcodeParser) Trigger() []byte {
+	return []byte{'{'}
+}
+
+func (pairedShortcodeParser) Open(_ ast.Node, reader text.Reader, _ parser.Context) (ast.Node, parser.State) {
+	line, _ := reader.PeekLine()
+	name, args, ok := parseShortcodeLine(line)
+	if !ok {
+		return nil, parser.NoChildren
+	}
+	if _, paired := pairedShortcodes[name]; !paired {
+		return nil, parser.NoChildren
+	}
+	advanceToEOL(reader, line)
+	return &pairedShortcodeNode{name: name, args: args}, parser.HasChildren
+}
+
+func (pairedShortcodeParser) Continue(node ast.Node, reader text.Reader, _ parser.Context) parser.State {
+	n := node.(*pairedShortcodeNode)
+	line, _ := reader.PeekLine()
+	name, _, ok := parseShortcodeLine(line)
+	if !ok || name != "/"+n.name {
+		return parser.Continue | parser.HasChildren
+	}
+	// The closing line belongs to the innermost open shortcode of the same
+	// name, which is continued after this one.
+	for child := n.LastChild(); child != nil; child = child.LastChild() {
+		if inner, ok := child.(*pairedShortcodeNode); ok && !inner.closed && inner.name == n.name {
+			return parser.Continue | parser.HasChildren
+		}
+	}
+	advanceToEOL(reader, line)
+	n.closed = true
+	return parser.Close
+}
+
+// advanceToEOL consumes line up to, but not including, its line ending, as
+// block parsers must leave the line ending to goldmark.
+func advanceToEOL(reader text.Reader, line []byte) {
+	reader.Advance(len(bytes.TrimRight(line, "\r\n")))
+}
+
+func (pairedShortcodeParser) Close(ast.Node, text.Reader, parser.Context) {}
+
+func (pairedShortcodeParser) CanInterruptParagraph() bool { return true }
+
+func (pairedShortcodeParser) CanAcceptIndentedLine() bool { return false }
+
+// shortcodeParser parses inline {{< name "arg" bare >}} shortcodes. Being an
+// inline parser, it never fires inside code spans or code blocks.
+type shortcodeParser struct{}
+
+func (shortcodeParser) Trigger() []byte {
+	return []byte{'{'}
+}
+
+func (shortcodeParser) Parse(_ ast.Node, block text.Reader, _ parser.Context) ast.Node {
+	line, _ := block.PeekLine()
+	if !bytes.HasPrefix(line, []byte("{{<")) {
+		return nil
+	}
+	end := bytes.Index(line, []byte(">}}"))
+	if end < 0 {
+		return nil
+	}
+
+	fields, err := splitShortcodeArgs(string(line[3:end]))
+	if err != nil || len(fields) == 0 {
+		return nil
+	}
+
+	raw := line[:end+3]
+	block.Advance(len(raw))
+	return &shortcodeNode{name: fields[0], args: fields[1:], raw: bytes.Clone(raw)}
+}
+
+// splitShortcodeArgs splits s on spaces, honoring Go-style double-quoted
+// strings.
+func splitShortcodeArgs(s string) ([]string, error) {
+	var fields []string
+	for {
+		s = strings.TrimLeftFunc(s, unicode.IsSpace)
+		if s == "" {
+			return fields, nil
+		}
+
+		if s[0] != '"' {
+			i := strings.IndexFunc(s, unicode.IsSpace)
+			if i < 0 {
+				i = len(s)
+			}
+			fields = append(fields, s[:i])
+			s = s[i:]
+			continue
+		}
+
+		quoted, err := strconv.QuotedPrefix(s)
+		if err != nil {
+			return nil, err
+		}
+		unquoted, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, unquoted)
+		s = s[len(quoted):]
+	}
+}
+
+// checkShortcodes reports shortcodes with the wrong number of arguments and
+// warns about unknown ones, which render as their literal source.
+func checkShortcodes(doc ast.Node, filename string) error {
+	return ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if p, ok := n.(*pairedShortcodeNode); ok {
+			if !p.closed {
+				warnf("shortcode %q in %s is never closed with {{< /%s >}}", p.name, filename, p.name)
+			}
+			if def := pairedShortcodes[p.name]; len(p.args) != def.args {
+				return ast.WalkStop, fmt.Errorf("shortcode %q in %s takes %d arguments, got %d", p.name, filename, def.args, len(p.args))
+			}
+			return ast.WalkContinue, nil
+		}
+		sc, ok := n.(*shortcodeNode)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+		if strings.HasPrefix(sc.name, "/") {
+			warnf("closing shortcode %q in %s has no opening shortcode", sc.name, filename)
+			return ast.WalkContinue, nil
+		}
+		if _, ok := pairedShortcodes[sc.name]; ok {
+			warnf("shortcode %q in %s must be on a line of its own", sc.name, filename)
+			return ast.WalkContinue, nil
+		}
+		def, ok := shortcodes[sc.name]
+		if !ok {
+			warnf("unknown shortcode %q in %s", sc.name, filename)
+			return ast.WalkContinue, nil
+		}
+		if len(sc.args) != def.args {
+			return ast.WalkStop, fmt.Errorf("shortcode %q in %s takes %d arguments, got %d", sc.name, filename, def.args, len(sc.args))
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+type shortcodeRenderer struct{}
+
+func (shortcodeRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindShortcode, func(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		n := node.(*shortcodeNode)
+		if def, ok := shortcodes[n.name]; ok {
+			def.render(w, n)
+		} else {
+			_, _ = w.Write(util.EscapeHTML(n.raw))
+		}
+		return ast.WalkSkipChildren, nil
+	})
+	reg.Register(kindPairedShortcode, func(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		n := node.(*pairedShortcodeNode)
+		def := pairedShortcodes[n.name]
+		if entering {
+			def.open(w, n.args)
+		} else {
+			def.close(w)
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// slugify lowercases s and joins its letter and digit runs with hyphens.
+// Non-ASCII letters and digits follow cfg.UnicodeSlugs. A string left
+// without any, like one of emoji, or of CJK with "strip", gets its
+// hashSlug instead, so only a blank s has an empty slug.
+func slugify(s string) string {
+	if slug := letterSlug(s); slug != "" || strings.TrimSpace(s) == "" {
+		return slug
+	}
+	return hashSlug(s)
+}
+
+// hashSlug returns a short slug for s derived from its hash, stable across
+// builds.
+func hashSlug(s string) string {
+	sum := sha256.Sum256([]byte(strings.ToLower(strings.TrimSpace(s))))
+	return hex.EncodeToString(sum[:4])
+}
+
+// letterSlug is slugify without the hashSlug fallback.
+func letterSlug(s string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			if r < utf8.RuneSelf {
+				b.WriteRune(r)
+			} else {
+				writeUnicodeSlugRune(&b, r)
+			}
+			hyphen = false
+		} else {
+			hyphen = true
+		}
+	}
+	return b.String()
+}
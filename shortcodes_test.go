@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		unicodeSlugs string
+		s            string
+		want         string
+	}{
+		{"keep", "Hello, World!", "hello-world"},
+		{"keep", "  Go 1.25  ", "go-1-25"},
+		{"keep", "Café", "café"},
+		{"keep", "日本語 Go", "日本語-go"},
+		{"keep", "🚀 Launch 🚀", "launch"},
+		{"strip", "Café", "caf"},
+		{"strip", "日本語 Go", "go"},
+		{"percent", "Café", "caf%C3%A9"},
+		{"percent", "日本", "%E6%97%A5%E6%9C%AC"},
+		// Nothing left to slug: a hash of the name, the same whatever its
+		// case or surrounding space.
+		{"keep", "🚀", hashSlug("🚀")},
+		{"strip", "日本語", hashSlug("日本語")},
+		{"keep", " 🚀 ", hashSlug("🚀")},
+		{"keep", "", ""},
+		{"keep", "  ", ""},
+	}
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	for _, tt := range tests {
+		cfg.UnicodeSlugs = tt.unicodeSlugs
+		if got := slugify(tt.s); got != tt.want {
+			t.Errorf("slugify(%q) with %s = %q, want %q", tt.s, tt.unicodeSlugs, got, tt.want)
+		}
+	}
+	if a, b := hashSlug("🚀"), hashSlug("🎉"); a == b || len(a) != 8 {
+		t.Errorf("hashSlug gives %q and %q, want distinct 8-character slugs", a, b)
+	}
+}
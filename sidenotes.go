@@ -0,0 +1,132 @@
+package main
+
+import (
+	"strconv"
+
+	"github.com/yuin/goldmark/ast"
+	east "github.com/yuin/goldmark/extension/ast"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"go.abhg.dev/goldmark/frontmatter"
+)
+
+// footnotePrefixAttr is the document attribute prefixAnchors stores its
+// prefix in, so footnote IDs in all.html are prefixed like headings.
+const footnotePrefixAttr = "footnote-prefix"
+
+// footnotePrefix returns the ID prefix of n's footnotes.
+func footnotePrefix(n ast.Node) []byte {
+	doc, ok := n.(*ast.Document)
+	if !ok {
+		doc = n.OwnerDocument()
+	}
+	if doc == nil {
+		return nil
+	}
+	v, _ := doc.AttributeString(footnotePrefixAttr)
+	prefix, _ := v.([]byte)
+	return prefix
+}
+
+var kindSidenote = ast.NewNodeKind("Sidenote")
+
+// sidenoteNode is a footnote moved next to the block that first refers to
+// it. Its children are the footnote's, backlinks included.
+type sidenoteNode struct {
+	ast.BaseBlock
+	index int
+}
+
+func (n *sidenoteNode) Kind() ast.NodeKind { return kindSidenote }
+
+func (n *sidenoteNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, map[string]string{"Index": strconv.Itoa(n.index)}, nil)
+}
+
+// sidenotes turns footnotes into sidenotes when cfg.Sidenotes or the
+// post's sidenotes front matter says so. Each footnote goes right after
+// the paragraph, or other block, of its first reference, and the list at
+// the bottom is dropped. It runs after goldmark's footnote transformer,
+// which numbers the footnotes and adds their backlinks.
+type sidenotes struct{}
+
+func (sidenotes) Transform(doc *ast.Document, _ text.Reader, pc parser.Context) {
+	enabled := cfg.Sidenotes
+	if d := frontmatter.Get(pc); d != nil {
+		var meta struct {
+			Sidenotes *bool `yaml:"sidenotes"`
+		}
+		if err := d.Decode(&meta); err == nil && meta.Sidenotes != nil {
+			enabled = *meta.Sidenotes
+		}
+	}
+	if !enabled {
+		return
+	}
+
+	list, ok := doc.LastChild().(*east.FootnoteList)
+	if !ok {
+		return
+	}
+	footnotes := map[int]*east.Footnote{}
+	for n := list.FirstChild(); n != nil; n = n.NextSibling() {
+		fn := n.(*east.Footnote)
+		footnotes[fn.Index] = fn
+	}
+
+	var links []*east.FootnoteLink
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if l, ok := n.(*east.FootnoteLink); ok && entering && l.RefIndex == 0 {
+			links = append(links, l)
+		}
+		return ast.WalkContinue, nil
+	})
+
+	// after is the last node inserted after each block, so the sidenotes
+	// of a block keep the order of their references.
+	after := map[ast.Node]ast.Node{}
+	for _, l := range links {
+		fn, ok := footnotes[l.Index]
+		if !ok {
+			continue
+		}
+		block := l.Parent()
+		for block.Type() != ast.TypeBlock {
+			block = block.Parent()
+		}
+
+		note := &sidenoteNode{index: l.Index}
+		for c := fn.FirstChild(); c != nil; {
+			next := c.NextSibling()
+			note.AppendChild(note, c)
+			c = next
+		}
+		prev := block
+		if a, ok := after[block]; ok {
+			prev = a
+		}
+		block.Parent().InsertAfter(block.Parent(), prev, note)
+		after[block] = note
+	}
+	doc.RemoveChild(doc, list)
+}
+
+type sidenoteRenderer struct{}
+
+func (sidenoteRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindSidenote, func(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		n := node.(*sidenoteNode)
+		if !entering {
+			_, _ = w.WriteString("</aside>\n")
+			return ast.WalkContinue, nil
+		}
+		index := strconv.Itoa(n.index)
+		_, _ = w.WriteString(`<aside class="sidenote" id="`)
+		_, _ = w.Write(footnotePrefix(n))
+		_, _ = w.WriteString("fn:" + index + `" role="doc-footnote">` + "\n")
+		_, _ = w.WriteString(`<span class="sidenote-number">` + index + "</span>\n")
+		return ast.WalkContinue, nil
+	})
+}
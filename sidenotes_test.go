@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const sidenotesFixture = "First[^a] and again[^a].\n\nSecond[^b].\n\n[^a]: Note a.\n[^b]: Note b.\n"
+
+// sidenotesHTML is sidenotesFixture with sidenotes, each right after the
+// paragraph of its first reference, numbered and linking back to every
+// reference.
+const sidenotesHTML = `<p>First<sup id="fnref:1"><a href="#fn:1" class="footnote-ref" role="doc-noteref">1</a></sup> and again<sup id="fnref1:1"><a href="#fn:1" class="footnote-ref" role="doc-noteref">1</a></sup>.</p>
+<aside class="sidenote" id="fn:1" role="doc-footnote">
+<span class="sidenote-number">1</span>
+<p>Note a.&#160;<a href="#fnref:1" class="footnote-backref" role="doc-backlink">&#x21a9;&#xfe0e;</a>&#160;<a href="#fnref1:1" class="footnote-backref" role="doc-backlink">&#x21a9;&#xfe0e;</a></p>
+</aside>
+<p>Second<sup id="fnref:2"><a href="#fn:2" class="footnote-ref" role="doc-noteref">2</a></sup>.</p>
+<aside class="sidenote" id="fn:2" role="doc-footnote">
+<span class="sidenote-number">2</span>
+<p>Note b.&#160;<a href="#fnref:2" class="footnote-backref" role="doc-backlink">&#x21a9;&#xfe0e;</a></p>
+</aside>
+`
+
+func TestSidenotes(t *testing.T) {
+	tests := []struct {
+		name      string
+		sidenotes bool
+		meta      string
+		want      bool
+	}{
+		{"enabled", true, "", true},
+		{"disabled", false, "", false},
+		{"enabled by the post", false, "sidenotes: true", true},
+		{"disabled by the post", true, "sidenotes: false", false},
+	}
+	useDefaultConfig(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.Sidenotes = tt.sidenotes
+			content := "---\ntitle: x\ndate: 2024-01-01\n" + tt.meta + "\n---\n\n" + sidenotesFixture
+			post, err := parsePost(cfg.ContentTypes[0], "x.md", []byte(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			html := string(post.Content)
+			if tt.want {
+				if html != sidenotesHTML {
+					t.Errorf("content =\n%s\nwant\n%s", html, sidenotesHTML)
+				}
+				if all := string(post.AllContent); !strings.Contains(all, `id="x-fn:1"`) || !strings.Contains(all, `href="#x-fnref:1"`) {
+					t.Errorf("all.html sidenotes are not prefixed:\n%s", all)
+				}
+				return
+			}
+			if strings.Contains(html, "sidenote") || !strings.Contains(html, `<div class="footnotes" role="doc-endnotes">`) {
+				t.Errorf("content has no footnote list at the bottom:\n%s", html)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+const (
+	socialImageWidth  = 1200
+	socialImageHeight = 630
+	socialImageMargin = 80
+
+	// Titles start at socialTitleMaxSize and shrink until they fit in
+	// socialTitleMaxLines, down to socialTitleMinSize.
+	socialTitleMaxSize  = 72
+	socialTitleMinSize  = 40
+	socialTitleMaxLines = 4
+	socialMetaSize      = 28
+)
+
+// generateSocialImages renders a 1200×630 card with the title for every
+// post without a cover, writing it to public/og/<slug>.png (or .jpeg) and
+// setting the post's SocialImage. It does nothing unless
+// cfg.SocialImages.Font is set.
+func generateSocialImages(ctx context.Context, posts []Post) error {
+	sc := cfg.SocialImages
+	if sc.Font == "" {
+		return nil
+	}
+
+	fontData, err := os.ReadFile(sc.Font)
+	if err != nil {
+		return fmt.Errorf("read social image font: %w", err)
+	}
+	fnt, err := opentype.Parse(fontData)
+	if err != nil {
+		return fmt.Errorf("parse social image font %s: %w", sc.Font, err)
+	}
+	bg, err := socialBackground(sc)
+	if err != nil {
+		return err
+	}
+	textColor, err := parseHexColor(sc.TextColor)
+	if err != nil {
+		return fmt.Errorf("socialImages.textColor: %w", err)
+	}
+
+	return parallel(ctx, len(posts), func(_ context.Context, i int) error {
+		post := posts[i]
+		if post.Cover != "" || post.FirstImage != "" {
+			return nil
+		}
+		img := image.NewRGBA(image.Rect(0, 0, socialImageWidth, socialImageHeight))
+		draw.Draw(img, img.Bounds(), bg, image.Point{}, draw.Src)
+		if err := drawSocialText(img, fnt, image.NewUniform(textColor), post); err != nil {
+			return fmt.Errorf("render social image for %s: %w", post.Slug, err)
+		}
+
+		rel := "og/" + strings.TrimSuffix(post.Path, ".html") + "." + sc.Format
+		path := filepath.Join("public", filepath.FromSlash(rel))
+		if err := mkdirOutput(filepath.Dir(path)); err != nil {
+			return fmt.Errorf("create og dir for %s: %w", path, err)
+		}
+		if err := writeImage(path, img, sc.Format); err != nil {
+			return err
+		}
+		posts[i].SocialImage = rel
+		return nil
+	})
+}
+
+// socialBackground returns the background image scaled and cropped to the
+// card size, or the solid background color when no image is configured.
+func socialBackground(sc socialImagesConfig) (image.Image, error) {
+	if sc.Background == "" {
+		c, err := parseHexColor(sc.BackgroundColor)
+		if err != nil {
+			return nil, fmt.Errorf("socialImages.backgroundColor: %w", err)
+		}
+		return image.NewUniform(c), nil
+	}
+
+	f, err := os.Open(sc.Background)
+	if err != nil {
+		return nil, fmt.Errorf("open social image background: %w", err)
+	}
+	defer f.Close()
+	src, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("decode social image background %s: %w", sc.Background, err)
+	}
+
+	// Crop the source to the card's aspect ratio around its center.
+	b := src.Bounds()
+	crop := b
+	if b.Dx()*socialImageHeight > b.Dy()*socialImageWidth {
+		w := b.Dy() * socialImageWidth / socialImageHeight
+		crop.Min.X += (b.Dx() - w) / 2
+		crop.Max.X = crop.Min.X + w
+	} else {
+		h := b.Dx() * socialImageHeight / socialImageWidth
+		crop.Min.Y += (b.Dy() - h) / 2
+		crop.Max.Y = crop.Min.Y + h
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, socialImageWidth, socialImageHeight))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, draw.Src, nil)
+	return dst, nil
+}
+
+// drawSocialText draws the wrapped title, vertically centered, and the
+// optional date and author line along the bottom margin.
+func drawSocialText(img draw.Image, fnt *opentype.Font, ink image.Image, post Post) error {
+	maxWidth := fixed.I(socialImageWidth - 2*socialImageMargin)
+
+	var face font.Face
+	var lines []string
+	for size := float64(socialTitleMaxSize); ; size -= 4 {
+		var err error
+		face, err = opentype.NewFace(fnt, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+		if err != nil {
+			return err
+		}
+		lines = wrapText(face, post.Title, maxWidth)
+		if len(lines) <= socialTitleMaxLines || size <= socialTitleMinSize {
+			break
+		}
+		face.Close()
+	}
+	defer face.Close()
+	if len(lines) > socialTitleMaxLines {
+		lines = lines[:socialTitleMaxLines]
+		lines[len(lines)-1] = truncateText(face, lines[len(lines)-1]+"…", maxWidth)
+	}
+
+	d := &font.Drawer{Dst: img, Src: ink, Face: face}
+	metrics := face.Metrics()
+	lineHeight := metrics.Height * 12 / 10
+	y := fixed.I(socialImageHeight/2) - lineHeight*fixed.Int26_6(len(lines))/2 + metrics.Ascent
+	for _, line := range lines {
+		d.Dot = fixed.Point26_6{X: fixed.I(socialImageMargin), Y: y}
+		d.DrawString(line)
+		y += lineHeight
+	}
+
+	var meta []string
+	if cfg.SocialImages.ShowDate {
+		meta = append(meta, post.DateString())
+	}
+	if cfg.SocialImages.ShowAuthor {
+		for _, a := range post.Authors {
+			meta = append(meta, a.Name)
+		}
+	}
+	if len(meta) == 0 {
+		return nil
+	}
+	metaFace, err := opentype.NewFace(fnt, &opentype.FaceOptions{Size: socialMetaSize, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return err
+	}
+	defer metaFace.Close()
+	d.Face = metaFace
+	d.Dot = fixed.Point26_6{X: fixed.I(socialImageMargin), Y: fixed.I(socialImageHeight - socialImageMargin)}
+	d.DrawString(truncateText(metaFace, strings.Join(meta, " · "), maxWidth))
+	return nil
+}
+
+// wrapText breaks s into lines no wider than maxWidth, at spaces where
+// possible. Words wider than a line are put on their own line.
+func wrapText(face font.Face, s string, maxWidth fixed.Int26_6) []string {
+	var lines []string
+	var line string
+	for _, word := range strings.Fields(s) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if line != "" && font.MeasureString(face, candidate) > maxWidth {
+			lines = append(lines, line)
+			candidate = word
+		}
+		line = candidate
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// truncateText shortens s with an ellipsis until it fits in maxWidth.
+func truncateText(face font.Face, s string, maxWidth fixed.Int26_6) string {
+	runes := []rune(strings.TrimSuffix(s, "…"))
+	for font.MeasureString(face, s) > maxWidth && len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		s = strings.TrimRight(string(runes), " ") + "…"
+	}
+	return s
+}
+
+// parseHexColor parses a #rrggbb color.
+func parseHexColor(s string) (color.RGBA, error) {
+	v, err := strconv.ParseUint(strings.TrimPrefix(s, "#"), 16, 32)
+	if err != nil || len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, want #rrggbb", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 0xff}, nil
+}
+
+// writeImage encodes img as format, "png" or "jpeg" at
+// cfg.ImageQuality.JPEG.
+func writeImage(path string, img image.Image, format string) error {
+	f, err := createOutput(path)
+	if err != nil {
+		return fmt.Errorf("create %s: %w", path, err)
+	}
+	defer f.Close()
+
+	if format == "jpeg" {
+		err = jpeg.Encode(f, img, &jpeg.Options{Quality: cfg.ImageQuality.JPEG})
+	} else {
+		err = png.Encode(f, img)
+	}
+	if err != nil {
+		return fmt.Errorf("encode %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// statePath is where the build records what it read and wrote, for the
+// next build to compare against.
+const statePath = ".build-state.json"
+
+// stateVersion is bumped whenever buildState changes incompatibly; older
+// state files are then ignored.
+const stateVersion = 2
+
+// buildState describes one build.
+type buildState struct {
+	Version int `json:"version"`
+	// Sources maps every input file to the SHA-256 of its content.
+	Sources map[string]string `json:"sources"`
+	// Outputs lists the files written to public, sorted.
+	Outputs []string `json:"outputs"`
+	// Posts caches what was parsed from each post, keyed by source path.
+	Posts map[string]postState `json:"posts"`
+	// Redirects maps removed page paths to their replacements, so they
+	// outlive the build that noticed the rename.
+	Redirects map[string]string `json:"redirects,omitempty"`
+	// Vanished lists the removed page paths still without a redirect, so
+	// they are reported until they get one.
+	Vanished []string `json:"vanished,omitempty"`
+}
+
+// postState is the part of a parsed post kept between builds.
+type postState struct {
+	Hash  string `json:"hash"`
+	Slug  string `json:"slug"`
+	Path  string `json:"path"`
+	Title string `json:"title"`
+	Date  string `json:"date"`
+	// Published is when the post was first listed, empty while it is
+	// not.
+	Published string `json:"published,omitempty"`
+}
+
+var (
+	// prevState is the previous build's state, nil for a clean build.
+	prevState *buildState
+
+	// outputs collects the files written through the output helpers.
+	outputsMu sync.Mutex
+	outputs   = map[string]bool{}
+)
+
+func recordOutput(path string) {
+	outputsMu.Lock()
+	defer outputsMu.Unlock()
+	outputs[filepath.ToSlash(path)] = true
+}
+
+// readState loads the previous build's state. A missing, unreadable or
+// outdated state file yields nil, so the build starts clean.
+func readState(path string) *buildState {
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		warnf("ignoring build state: %v", err)
+		return nil
+	}
+
+	var s buildState
+	if err := json.Unmarshal(content, &s); err != nil {
+		warnf("ignoring corrupt build state %s: %v", path, err)
+		return nil
+	}
+	if s.Version != stateVersion {
+		return nil
+	}
+	return &s
+}
+
+// writeState records the inputs and outputs of this build.
+func writeState(path string, posts []Post, redirects map[string]string, vanished []string, sourceDirs []string) error {
+	s := buildState{
+		Version:   stateVersion,
+		Sources:   map[string]string{},
+		Posts:     map[string]postState{},
+		Redirects: redirects,
+		Vanished:  vanished,
+	}
+
+	for _, dir := range sourceDirs {
+		err := filepath.WalkDir(dir, func(src string, entry fs.DirEntry, err error) error {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			if err != nil || entry.IsDir() {
+				return err
+			}
+			hash, err := hashFile(src)
+			if err != nil {
+				return err
+			}
+			s.Sources[filepath.ToSlash(src)] = hash
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("hash sources in %s: %w", dir, err)
+		}
+	}
+
+	for _, post := range posts {
+		src := filepath.ToSlash(filepath.Join(post.Type.Dir, post.Filename))
+		s.Posts[src] = postState{
+			Hash:  s.Sources[src],
+			Slug:  post.Slug,
+			Path:  post.Path,
+			Title: post.Title,
+			Date:  post.DateRFC3339(),
+		}
+		if !post.FirstPublished.IsZero() {
+			ps := s.Posts[src]
+			ps.Published = post.FirstPublished.Format(time.RFC3339)
+			s.Posts[src] = ps
+		}
+	}
+
+	outputsMu.Lock()
+	for out := range outputs {
+		s.Outputs = append(s.Outputs, out)
+	}
+	outputsMu.Unlock()
+	sort.Strings(s.Outputs)
+
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode build state: %w", err)
+	}
+	if err := os.WriteFile(path, append(content, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write build state: %w", err)
+	}
+	return nil
+}
+
+// setFirstPublished sets FirstPublished on every publishable post: the
+// time recorded by an earlier build if there is one, matching renamed
+// posts by title and date, or else the build time. Without a previous
+// state there is no history, and posts count as published on their date.
+func setFirstPublished(posts []Post, prev *buildState) {
+	for i, post := range posts {
+		if !post.Publishable() {
+			continue
+		}
+		if prev == nil {
+			posts[i].FirstPublished = post.Date
+			continue
+		}
+		posts[i].FirstPublished = buildTime
+		if published, ok := previouslyPublished(prev, post); ok {
+			posts[i].FirstPublished = published
+		}
+	}
+}
+
+// previouslyPublished returns when the previous build recorded post as
+// first published.
+func previouslyPublished(prev *buildState, post Post) (time.Time, bool) {
+	src := filepath.ToSlash(filepath.Join(post.Type.Dir, post.Filename))
+	old, ok := prev.Posts[src]
+	if !ok {
+		// A renamed post is recognized by its title and date, as long as
+		// no other post shares them.
+		matches := 0
+		for _, ps := range prev.Posts {
+			if ps.Title == post.Title && ps.Date == post.DateRFC3339() && ps.Published != "" {
+				old = ps
+				matches++
+			}
+		}
+		ok = matches == 1
+	}
+	if !ok || old.Published == "" {
+		return time.Time{}, false
+	}
+	published, err := time.Parse(time.RFC3339, old.Published)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return published, true
+}
+
+func hashFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), nil
+}
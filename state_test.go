@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestFirstPublishedRoundTrip writes the state of one build and checks what
+// the next build, reading it back, makes of each post's first publication.
+func TestFirstPublishedRoundTrip(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 1, d, 0, 0, 0, 0, time.UTC) }
+	dir := t.TempDir()
+	posts := filepath.Join(dir, "posts")
+	if err := os.Mkdir(posts, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for _, file := range []string{"kept.md", "renamed.md"} {
+		if err := os.WriteFile(filepath.Join(posts, file), []byte(file), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	savedTime := buildTime
+	t.Cleanup(func() { buildTime = savedTime })
+	ct := contentType{Dir: posts}
+
+	buildTime = day(10)
+	first := []Post{
+		{Title: "kept", Filename: "kept.md", Date: day(1), Type: ct},
+		{Title: "renamed", Filename: "renamed.md", Date: day(2), Type: ct},
+		{Title: "hidden", Filename: "hidden.md", Date: day(3), Type: ct, Unlisted: true},
+	}
+	setFirstPublished(first, nil)
+	for _, post := range first[:2] {
+		if !post.FirstPublished.Equal(post.Date) {
+			t.Errorf("without a previous state, %s first published %s, want its date %s", post.Title, post.FirstPublished, post.Date)
+		}
+	}
+	if !first[2].FirstPublished.IsZero() {
+		t.Errorf("unlisted post first published %s, want never", first[2].FirstPublished)
+	}
+	statePath := filepath.Join(dir, statePath)
+	if err := writeState(statePath, first, nil, nil, []string{posts}); err != nil {
+		t.Fatal(err)
+	}
+	prev := readState(statePath)
+	if prev == nil {
+		t.Fatal("readState returned nil for the state just written")
+	}
+
+	buildTime = day(20)
+	second := []Post{
+		{Title: "kept", Filename: "kept.md", Date: day(1), Type: ct},
+		{Title: "renamed", Filename: "moved.md", Date: day(2), Type: ct},
+		// Published now, with its old date.
+		{Title: "hidden", Filename: "hidden.md", Date: day(3), Type: ct},
+		{Title: "new", Filename: "new.md", Date: day(15), Type: ct},
+	}
+	setFirstPublished(second, prev)
+	want := map[string]time.Time{"kept": day(1), "renamed": day(2), "hidden": day(20), "new": day(20)}
+	for _, post := range second {
+		if !post.FirstPublished.Equal(want[post.Title]) {
+			t.Errorf("%s first published %s, want %s", post.Title, post.FirstPublished, want[post.Title])
+		}
+	}
+}
+
+func TestReadState(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantNil bool
+		warn    bool
+	}{
+		{"current", `{"version": 2, "posts": {"posts/a.md": {"slug": "a", "published": "2024-01-01T00:00:00Z"}}}`, false, false},
+		{"outdated", `{"version": 1}`, true, false},
+		{"corrupt", `{"version": 2,`, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), statePath)
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			before := warnings
+			s := readState(path)
+			if (s == nil) != tt.wantNil {
+				t.Errorf("readState = %+v, want nil: %v", s, tt.wantNil)
+			}
+			if got := warnings > before; got != tt.warn {
+				t.Errorf("warned: %v, want %v", got, tt.warn)
+			}
+		})
+	}
+	if s := readState(filepath.Join(t.TempDir(), statePath)); s != nil {
+		t.Errorf("readState of a missing file = %+v, want nil", s)
+	}
+}
@@ -0,0 +1,12 @@
+// Copies a code block to the clipboard from its copy button. Blocks with a
+// data-copy attribute copy that instead of their text, e.g. shell sessions
+// without their prompts and output.
+document.querySelectorAll(".code-copy").forEach((button) => {
+  button.addEventListener("click", async () => {
+    const block = button.parentElement;
+    const text = block.dataset.copy ?? block.querySelector("pre").innerText;
+    await navigator.clipboard.writeText(text);
+    button.textContent = "Copied";
+    setTimeout(() => (button.textContent = "Copy"), 2000);
+  });
+});
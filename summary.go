@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"html"
+	"html/template"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// renderSummary renders the post summary: the blocks under the heading
+// named by from up to the next heading, or, when from is empty or no
+// heading matches, the description or else the first paragraph.
+func renderSummary(doc ast.Node, source []byte, from, description string) (template.HTML, error) {
+	var blocks []ast.Node
+	if from != "" {
+		blocks = sectionBlocks(doc, source, from)
+	}
+	if blocks == nil {
+		if description != "" {
+			return template.HTML(template.HTMLEscapeString(description)), nil
+		}
+		for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+			if n.Kind() == ast.KindParagraph {
+				blocks = []ast.Node{n}
+				break
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, n := range blocks {
+		if err := md.Renderer().Render(&buf, source, n); err != nil {
+			return "", err
+		}
+	}
+	return template.HTML(strings.TrimSpace(buf.String())), nil
+}
+
+// sectionBlocks returns the top-level blocks following the first heading
+// whose text matches heading, case-insensitively, up to the next heading.
+func sectionBlocks(doc ast.Node, source []byte, heading string) []ast.Node {
+	var blocks []ast.Node
+	found := false
+	for n := doc.FirstChild(); n != nil; n = n.NextSibling() {
+		if n.Kind() == ast.KindHeading {
+			if found {
+				break
+			}
+			found = strings.EqualFold(strings.TrimSpace(nodeText(n, source)), strings.TrimSpace(heading))
+			continue
+		}
+		if found {
+			blocks = append(blocks, n)
+		}
+	}
+	if !found {
+		return nil
+	}
+	return blocks
+}
+
+// nodeText concatenates the text inside n.
+func nodeText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n := n.(type) {
+		case *ast.Text:
+			b.Write(n.Segment.Value(source))
+			if n.SoftLineBreak() {
+				b.WriteByte(' ')
+			}
+		case *ast.String:
+			b.Write(n.Value)
+		}
+		return ast.WalkContinue, nil
+	})
+	return b.String()
+}
+
+// plainText strips the tags from rendered HTML, unescapes entities and
+// collapses whitespace. Block tags separate words; inline ones like <em>
+// do not, so punctuation after them stays in place.
+func plainText(s template.HTML) string {
+	var b, tag strings.Builder
+	inTag := false
+	for _, r := range string(s) {
+		switch {
+		case r == '<':
+			inTag = true
+			tag.Reset()
+		case r == '>' && inTag:
+			inTag = false
+			if m := tagPattern.FindStringSubmatch("<" + tag.String()); m != nil && blockTags[strings.ToLower(m[2])] {
+				b.WriteByte(' ')
+			}
+		case inTag:
+			tag.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return strings.Join(strings.Fields(html.UnescapeString(b.String())), " ")
+}
+
+// blockTags are the elements plainText separates from the text around them.
+var blockTags = map[string]bool{
+	"address": true, "blockquote": true, "br": true, "dd": true, "div": true,
+	"dl": true, "dt": true, "figcaption": true, "figure": true, "h1": true,
+	"h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "hr": true,
+	"li": true, "ol": true, "p": true, "pre": true, "section": true,
+	"table": true, "td": true, "th": true, "tr": true, "ul": true,
+}
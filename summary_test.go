@@ -0,0 +1,74 @@
+package main
+
+import (
+	"html/template"
+	"testing"
+)
+
+func TestSummaryFormatting(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		wantHTML template.HTML
+		wantText string
+	}{
+		{
+			"inline formatting",
+			"Some **bold**, *emphasis*, `code` and a [link](/a.html).\n\nSecond paragraph.",
+			`<p>Some <strong>bold</strong>, <em>emphasis</em>, <code>code</code> and a <a href="/a.html">link</a>.</p>`,
+			"Some bold, emphasis, code and a link.",
+		},
+		{
+			"entities",
+			"Fish & chips < \"steak\".",
+			`<p>Fish &amp; chips &lt; &quot;steak&quot;.</p>`,
+			`Fish & chips < "steak".`,
+		},
+		{
+			"line break",
+			"One  \ntwo.",
+			"<p>One<br>\ntwo.</p>",
+			"One two.",
+		},
+		{
+			"plain",
+			"Just text.",
+			"<p>Just text.</p>",
+			"Just text.",
+		},
+	}
+	useDefaultConfig(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post, err := parsePost(cfg.ContentTypes[0], "x.md", []byte("---\ntitle: x\ndate: 2024-01-01\n---\n\n"+tt.body+"\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if post.SummaryHTML != tt.wantHTML {
+				t.Errorf("SummaryHTML = %q, want %q", post.SummaryHTML, tt.wantHTML)
+			}
+			if post.SummaryText != tt.wantText {
+				t.Errorf("SummaryText = %q, want %q", post.SummaryText, tt.wantText)
+			}
+		})
+	}
+}
+
+func TestPlainText(t *testing.T) {
+	tests := []struct {
+		html template.HTML
+		want string
+	}{
+		{"<p>a</p><p>b</p>", "a b"},
+		{"a<br>b", "a b"},
+		{"<ul><li>a</li><li>b</li></ul>", "a b"},
+		{"<em>a</em>, <strong>b</strong>.", "a, b."},
+		{"<a href=\"/x.html\" title=\"t\">link</a>s", "links"},
+		{"  spaced \n\t out  ", "spaced out"},
+	}
+	for _, tt := range tests {
+		if got := plainText(tt.html); got != tt.want {
+			t.Errorf("plainText(%q) = %q, want %q", tt.html, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// TagCount is one entry of public/api/tags.json.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Slug  string `json:"slug"`
+	Count int    `json:"count"`
+}
+
+// hashedTags holds the tags already reported as having a hashSlug.
+var hashedTags sync.Map
+
+// normalizeTags trims the front matter tags and drops empty and duplicate
+// ones, comparing slugs so "Go" and "go" count once. Tags with no letters
+// or digits to slug, like emoji, keep a hashed slug, which is reported
+// once per build.
+func normalizeTags(tags []string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		slug := slugify(tag)
+		if slug == "" || seen[slug] {
+			continue
+		}
+		if letterSlug(tag) == "" {
+			if _, reported := hashedTags.LoadOrStore(tag, true); !reported {
+				warnf("tag %q has no letters or digits to slug, so its page is %s.html", tag, slug)
+			}
+		}
+		seen[slug] = true
+		out = append(out, tag)
+	}
+	return out
+}
+
+// collectTags counts the posts per tag; see countTags.
+func collectTags(posts []Post) []TagCount {
+	return countTags(posts, func(p Post) []string { return p.Tags })
+}
+
+// countTags counts the posts per term returned by terms, keyed by slug. The
+// spelling of a term's first use is kept. Terms are sorted by descending
+// count, then name.
+func countTags(posts []Post, terms func(Post) []string) []TagCount {
+	bySlug := map[string]*TagCount{}
+	for _, post := range posts {
+		for _, tag := range terms(post) {
+			slug := slugify(tag)
+			tc, ok := bySlug[slug]
+			if !ok {
+				tc = &TagCount{Tag: tag, Slug: slug}
+				bySlug[slug] = tc
+			}
+			tc.Count++
+		}
+	}
+
+	tags := make([]TagCount, 0, len(bySlug))
+	for _, tc := range bySlug {
+		tags = append(tags, *tc)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if tags[i].Count != tags[j].Count {
+			return tags[i].Count > tags[j].Count
+		}
+		return tags[i].Tag < tags[j].Tag
+	})
+	return tags
+}
+
+// generateTagsJSON writes public/api/tags.json for client-side tag
+// filtering. It is written on every build, as [] when no post has tags.
+func generateTagsJSON(posts []Post) error {
+	if err := mkdirOutput("public/api"); err != nil {
+		return fmt.Errorf("create api dir: %w", err)
+	}
+
+	content, err := json.Marshal(collectTags(posts))
+	if err != nil {
+		return fmt.Errorf("encode tags: %w", err)
+	}
+	if err := writeOutput("public/api/tags.json", append(content, '\n')); err != nil {
+		return fmt.Errorf("write tags: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name         string
+		unicodeSlugs string
+		tags         []string
+		want         []string
+		warnings     int
+	}{
+		{"trimmed", "keep", []string{" go ", "Go", "rust"}, []string{"go", "rust"}, 0},
+		{"blank dropped", "keep", []string{"", "  ", "go"}, []string{"go"}, 0},
+		{"cjk kept", "keep", []string{"日本語"}, []string{"日本語"}, 0},
+		{"cjk stripped", "strip", []string{"中文", "한국어"}, []string{"中文", "한국어"}, 2},
+		{"emoji", "keep", []string{"🚀", "🎉"}, []string{"🚀", "🎉"}, 2},
+		{"emoji reported once", "keep", []string{"🚀"}, []string{"🚀"}, 0},
+	}
+	saved := cfg
+	t.Cleanup(func() { cfg = saved })
+	hashedTags.Clear()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.UnicodeSlugs = tt.unicodeSlugs
+			before := warnings
+			if got := normalizeTags(tt.tags); !slices.Equal(got, tt.want) {
+				t.Errorf("normalizeTags(%q) = %q, want %q", tt.tags, got, tt.want)
+			}
+			if got := warnings - before; got != tt.warnings {
+				t.Errorf("reported %d, want %d", got, tt.warnings)
+			}
+		})
+	}
+}
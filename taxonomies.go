@@ -0,0 +1,210 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"strings"
+)
+
+// TaxonomyData is rendered by taxonomy templates, both for a term's page,
+// with Term and Posts set, and for the taxonomy's page listing its Terms.
+type TaxonomyData struct {
+	Taxonomy string
+	Term     string
+	// PageURL is the page's site-relative URL, and FeedURL that of the
+	// term's feed when the taxonomy has feeds.
+	PageURL string
+	FeedURL string
+	Posts   []Post
+	Terms   []TagCount
+	// URLPrefix is where the taxonomy's term pages live, e.g. "tags/".
+	URLPrefix string
+	GAID      string
+}
+
+// taxonomyTerms reads the terms of every configured taxonomy from the front
+// matter. Taxonomies no term is given for are left out. Terms slugified to
+// "index" are rejected, since their page would replace the taxonomy's
+// <urlPrefix>index.html.
+func taxonomyTerms(raw map[string]any) (map[string][]string, error) {
+	terms := map[string][]string{}
+	for _, tx := range cfg.Taxonomies {
+		values, err := termList(raw, tx.Name)
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range values {
+			if slugify(v) == "index" {
+				return nil, fmt.Errorf("%s: term %q would overwrite the %s index page", tx.Name, v, tx.Name)
+			}
+		}
+		if len(values) > 0 {
+			terms[tx.Name] = values
+		}
+	}
+	return terms, nil
+}
+
+// checkTaxonomyValues warns about terms outside their taxonomy's Values.
+// Terms are compared by slug, as on their pages.
+func checkTaxonomyValues(filename string, terms map[string][]string) {
+	for _, tx := range cfg.Taxonomies {
+		if len(tx.Values) == 0 {
+			continue
+		}
+		allowed := map[string]bool{}
+		for _, v := range tx.Values {
+			allowed[slugify(v)] = true
+		}
+		for _, term := range terms[tx.Name] {
+			if !allowed[slugify(term)] {
+				warnf("%s: unexpected %s %q, want one of %s", filename, tx.Name, term, strings.Join(tx.Values, ", "))
+			}
+		}
+	}
+}
+
+// Badge is a post's term in a taxonomy with badges, linking to the term's
+// page.
+type Badge struct {
+	Taxonomy string
+	Term     string
+	URL      string
+}
+
+// Badges returns the post's terms in every taxonomy with Badge set.
+func (p Post) Badges() []Badge {
+	var badges []Badge
+	for _, tx := range cfg.Taxonomies {
+		if !tx.Badge {
+			continue
+		}
+		for _, term := range p.Taxonomies[tx.Name] {
+			badges = append(badges, Badge{
+				Taxonomy: tx.Name,
+				Term:     term,
+				URL:      "/" + tx.URLPrefix + slugify(term) + ".html",
+			})
+		}
+	}
+	return badges
+}
+
+// termList returns the normalized terms under key, which may be a single
+// term or a list of them. Numbers and booleans are taken as written, like
+// `tags: [2025]`.
+func termList(raw map[string]any, key string) ([]string, error) {
+	var values []string
+	switch v := raw[key].(type) {
+	case nil:
+	case []any:
+		for _, item := range v {
+			s, err := termString(key, item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, s)
+		}
+	default:
+		s, err := termString(key, v)
+		if err != nil {
+			return nil, err
+		}
+		values = []string{s}
+	}
+	return normalizeTags(values), nil
+}
+
+func termString(key string, v any) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case int, int64, uint64, float64, bool:
+		return fmt.Sprint(v), nil
+	default:
+		return "", fmt.Errorf("%s: terms must be strings, got %v", key, v)
+	}
+}
+
+// generateTaxonomies writes, for every taxonomy with terms, a page per term
+// at <urlPrefix><slug>.html, optionally with a feed at <urlPrefix><slug>.xml,
+// and <urlPrefix>index.html listing the terms. Taxonomies no post uses
+// produce nothing.
+func generateTaxonomies(posts []Post) error {
+	for _, tx := range cfg.Taxonomies {
+		terms := countTags(posts, func(p Post) []string { return p.Taxonomies[tx.Name] })
+		if len(terms) == 0 {
+			continue
+		}
+		dir := filepath.Join("public", filepath.FromSlash(tx.URLPrefix))
+		if err := mkdirOutput(dir); err != nil {
+			return fmt.Errorf("create %s dir: %w", tx.Name, err)
+		}
+
+		tmpl := mustLookupHTML(tx.Template)
+		for _, term := range terms {
+			data := TaxonomyData{
+				Taxonomy:  tx.Name,
+				Term:      term.Tag,
+				PageURL:   "/" + tx.URLPrefix + term.Slug + ".html",
+				Posts:     postsWithTerm(posts, tx.Name, term.Slug),
+				URLPrefix: tx.URLPrefix,
+				GAID:      gaID,
+			}
+			if tx.Feed {
+				data.FeedURL = "/" + tx.URLPrefix + term.Slug + ".xml"
+				inFeeds := postsWhere(data.Posts, func(p Post) bool { return p.Type.Feed && p.InFeed })
+				feed := feedFile{
+					Path:  data.FeedURL,
+					Title: siteTitle + " – " + term.Tag,
+					Posts: limitPosts(feedOrder(inFeeds), cfg.Feeds.RecentLimit),
+				}
+				if err := writeFeed(feed); err != nil {
+					return err
+				}
+			}
+			if err := renderTaxonomyPage(tmpl, data); err != nil {
+				return err
+			}
+		}
+
+		index := TaxonomyData{
+			Taxonomy:  tx.Name,
+			PageURL:   "/" + tx.URLPrefix + "index.html",
+			Terms:     terms,
+			URLPrefix: tx.URLPrefix,
+			GAID:      gaID,
+		}
+		if err := renderTaxonomyPage(taxonomyIndexTmpl, index); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// postsWithTerm returns the posts filed under the term with the given slug.
+func postsWithTerm(posts []Post, taxonomy, slug string) []Post {
+	return postsWhere(posts, func(p Post) bool {
+		for _, term := range p.Taxonomies[taxonomy] {
+			if slugify(term) == slug {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+func renderTaxonomyPage(tmpl *template.Template, data TaxonomyData) error {
+	path := filepath.Join("public", filepath.FromSlash(data.PageURL))
+	f, err := createOutput(path)
+	if err != nil {
+		return fmt.Errorf("create %s page %s: %w", data.Taxonomy, path, err)
+	}
+	defer f.Close()
+
+	if err := tmpl.Execute(f, data); err != nil {
+		return fmt.Errorf("render %s page %s: %w", data.Taxonomy, path, err)
+	}
+	return nil
+}
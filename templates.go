@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+)
+
+// loadTemplates parses every file under dir, naming each template by its
+// slash-separated path relative to dir, e.g. "partials/nav.gohtml". Files
+// ending in .gohtml form the HTML set; all others form the text set used
+// for XML output. Templates of a set can include each other by those
+// names, and a reference to a template that does not exist is an error.
+func loadTemplates(dir string) (*template.Template, *texttemplate.Template, error) {
+	htmlSet := template.New("").Funcs(siteFuncs)
+	textSet := texttemplate.New("").Funcs(siteFuncs).Funcs(xmlFuncs)
+
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		if strings.HasSuffix(name, ".gohtml") {
+			_, err = htmlSet.New(name).Parse(string(content))
+		} else {
+			_, err = textSet.New(name).Parse(string(content))
+		}
+		return err
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("load templates: %w", err)
+	}
+
+	for _, t := range htmlSet.Templates() {
+		if err := checkTemplateRefs(t.Name(), t.Tree, func(name string) bool { return htmlSet.Lookup(name) != nil }); err != nil {
+			return nil, nil, err
+		}
+	}
+	for _, t := range textSet.Templates() {
+		if err := checkTemplateRefs(t.Name(), t.Tree, func(name string) bool { return textSet.Lookup(name) != nil }); err != nil {
+			return nil, nil, err
+		}
+	}
+	return htmlSet, textSet, nil
+}
+
+// checkTemplateRefs reports the first {{template}} call in tree naming a
+// template for which exists returns false.
+func checkTemplateRefs(name string, tree *parse.Tree, exists func(string) bool) error {
+	if tree == nil || tree.Root == nil {
+		return nil
+	}
+
+	var check func(n parse.Node) error
+	check = func(n parse.Node) error {
+		switch n := n.(type) {
+		case *parse.ListNode:
+			if n == nil {
+				return nil
+			}
+			for _, child := range n.Nodes {
+				if err := check(child); err != nil {
+					return err
+				}
+			}
+		case *parse.IfNode:
+			return checkBranch(check, &n.BranchNode)
+		case *parse.RangeNode:
+			return checkBranch(check, &n.BranchNode)
+		case *parse.WithNode:
+			return checkBranch(check, &n.BranchNode)
+		case *parse.TemplateNode:
+			if !exists(n.Name) {
+				return fmt.Errorf("template %s references missing template %q", name, n.Name)
+			}
+		}
+		return nil
+	}
+	return check(tree.Root)
+}
+
+func checkBranch(check func(parse.Node) error, b *parse.BranchNode) error {
+	if err := check(b.List); err != nil {
+		return err
+	}
+	if b.ElseList != nil {
+		return check(b.ElseList)
+	}
+	return nil
+}
+
+func mustLoadTemplates(dir string) (*template.Template, *texttemplate.Template) {
+	htmlSet, textSet, err := loadTemplates(dir)
+	if err != nil {
+		panic(err)
+	}
+	return htmlSet, textSet
+}
+
+func mustLookupHTML(name string) *template.Template {
+	t := htmlTemplates.Lookup(name)
+	if t == nil {
+		panic(fmt.Sprintf("template %q not found", name))
+	}
+	return t
+}
+
+func mustLookupText(name string) *texttemplate.Template {
+	t := textTemplates.Lookup(name)
+	if t == nil {
+		panic(fmt.Sprintf("template %q not found", name))
+	}
+	return t
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/renderer"
+	"github.com/yuin/goldmark/util"
+)
+
+// tocMarker, on a line of its own, is replaced with the table of contents,
+// as is the toc shortcode.
+const tocMarker = "[[TOC]]"
+
+var kindTOC = ast.NewNodeKind("TOC")
+
+// tocNode renders the table of contents in place of a marker paragraph.
+type tocNode struct {
+	ast.BaseBlock
+	sections []Section
+	// prefix is prepended to the section anchors; see prefixAnchors.
+	prefix string
+}
+
+func (n *tocNode) Kind() ast.NodeKind { return kindTOC }
+
+func (n *tocNode) Dump(source []byte, level int) {
+	ast.DumpHelper(n, source, level, nil, nil)
+}
+
+// placeTOC replaces the first table of contents marker with the sections
+// and reports whether there was one. Further markers are dropped with a
+// warning.
+func placeTOC(doc ast.Node, source []byte, sections []Section, filename string) bool {
+	var markers []ast.Node
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		if p, ok := n.(*ast.Paragraph); ok && isTOCMarker(p, source) {
+			markers = append(markers, p)
+			return ast.WalkSkipChildren, nil
+		}
+		if sc, ok := n.(*shortcodeNode); ok && sc.name == "toc" {
+			warnf("shortcode %q in %s must be on a line of its own", sc.name, filename)
+		}
+		return ast.WalkContinue, nil
+	})
+	if len(markers) == 0 {
+		return false
+	}
+	if len(markers) > 1 {
+		warnf("%s has %d table of contents markers; only the first is used", filename, len(markers))
+	}
+
+	for i, marker := range markers {
+		parent := marker.Parent()
+		if i == 0 {
+			parent.ReplaceChild(parent, marker, &tocNode{sections: sections})
+		} else {
+			parent.RemoveChild(parent, marker)
+		}
+	}
+	return true
+}
+
+// isTOCMarker reports whether p holds nothing but [[TOC]] or {{< toc >}}.
+func isTOCMarker(p *ast.Paragraph, source []byte) bool {
+	if sc, ok := p.FirstChild().(*shortcodeNode); ok && p.ChildCount() == 1 {
+		return sc.name == "toc"
+	}
+	for c := p.FirstChild(); c != nil; c = c.NextSibling() {
+		if _, ok := c.(*ast.Text); !ok {
+			return false
+		}
+	}
+	return strings.TrimSpace(nodeText(p, source)) == tocMarker
+}
+
+type tocRenderer struct{}
+
+func (tocRenderer) RegisterFuncs(reg renderer.NodeRendererFuncRegisterer) {
+	reg.Register(kindTOC, func(w util.BufWriter, _ []byte, node ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		n := node.(*tocNode)
+		_, _ = w.WriteString("<nav class=\"toc\" aria-label=\"Table of contents\">\n<ul>\n")
+		for _, s := range n.sections {
+			fmt.Fprintf(w, "<li><a href=\"#%s%s\">%s</a></li>\n", n.prefix, util.EscapeHTML([]byte(s.ID)), util.EscapeHTML([]byte(s.Title)))
+		}
+		_, _ = w.WriteString("</ul>\n</nav>\n")
+		return ast.WalkSkipChildren, nil
+	})
+}
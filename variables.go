@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/yuin/goldmark/ast"
+)
+
+// siteVarPattern matches a {{ site.name }} variable, which may continue
+// with a template pipeline like {{ site.name | printf "%q" }}.
+var siteVarPattern = regexp.MustCompile(`\{\{-?\s*site\.[^}]*\}\}`)
+
+// expandSiteVars replaces the {{ site.name }} variables in a post's
+// markdown with values from the site config map. doc is content already
+// parsed, which locates the code to skip. Variables in code and in the
+// front matter are left literal, as are ones that fail to evaluate, which
+// are reported. It reports whether anything was replaced, in which case
+// the result must be parsed again, since values may hold markdown.
+func expandSiteVars(filename string, content []byte, doc ast.Node) ([]byte, bool) {
+	matches := siteVarPattern.FindAllIndex(content, -1)
+	if matches == nil {
+		return content, false
+	}
+
+	skip := codeRanges(doc, content)
+	var out bytes.Buffer
+	last := 0
+	for _, m := range matches {
+		if inRanges(skip, m[0]) {
+			continue
+		}
+		value, err := evalSiteVar(string(content[m[0]:m[1]]))
+		if err != nil {
+			warnf("variable %s in %s: %v", content[m[0]:m[1]], filename, err)
+			continue
+		}
+		out.Write(content[last:m[0]])
+		out.WriteString(value)
+		last = m[1]
+	}
+	if last == 0 {
+		return content, false
+	}
+	out.Write(content[last:])
+	return out.Bytes(), true
+}
+
+// evalSiteVar executes action as a text/template with only the site
+// function available. Missing keys are errors rather than empty output.
+func evalSiteVar(action string) (string, error) {
+	tmpl, err := template.New("site").
+		Funcs(template.FuncMap{"site": func() map[string]any { return cfg.Site }}).
+		Option("missingkey=error").
+		Parse(action)
+	if err != nil {
+		return "", err
+	}
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// codeRanges returns the sorted byte ranges of content, parsed as doc,
+// that must not be expanded: the front matter, code blocks and code spans.
+func codeRanges(doc ast.Node, content []byte) [][2]int {
+	var ranges [][2]int
+	if end := frontMatterEnd(content); end > 0 {
+		ranges = append(ranges, [2]int{0, end})
+	}
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch n.(type) {
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			lines := n.Lines()
+			if lines.Len() > 0 {
+				ranges = append(ranges, [2]int{lines.At(0).Start, lines.At(lines.Len() - 1).Stop})
+			}
+			return ast.WalkSkipChildren, nil
+		case *ast.CodeSpan:
+			start, stop := -1, -1
+			for c := n.FirstChild(); c != nil; c = c.NextSibling() {
+				if t, ok := c.(*ast.Text); ok {
+					if start < 0 {
+						start = t.Segment.Start
+					}
+					stop = t.Segment.Stop
+				}
+			}
+			if start >= 0 {
+				ranges = append(ranges, [2]int{start, stop})
+			}
+			return ast.WalkSkipChildren, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+	return ranges
+}
+
+func inRanges(ranges [][2]int, pos int) bool {
+	for _, r := range ranges {
+		if pos >= r[0] && pos < r[1] {
+			return true
+		}
+	}
+	return false
+}